@@ -0,0 +1,148 @@
+package gofit
+
+import (
+	"io"
+	"math"
+	"time"
+)
+
+// Activity is an activity file decoded into its messages, with the messages
+// most analyses need grouped by type. Each group is in file order.
+type Activity struct {
+	FileID   *Message
+	Activity *Message
+
+	Sessions    []*Message
+	Laps        []*Message
+	Records     []*Message
+	Events      []*Message
+	DeviceInfos []*Message
+	Lengths     []*Message
+
+	// Every message in the file in order, including those above
+	Messages []*Message
+}
+
+// ReadActivity decodes every message of an activity file. If decoding fails
+// part way through the messages decoded so far are returned with the error.
+func ReadActivity(input io.Reader) (*Activity, error) {
+	a := &Activity{}
+
+	d := NewDecoder(input)
+	for {
+		m, err := d.Next()
+		if err == io.EOF {
+			return a, nil
+		}
+		if err != nil {
+			return a, err
+		}
+
+		a.add(m)
+	}
+}
+
+func (a *Activity) add(m *Message) {
+	a.Messages = append(a.Messages, m)
+
+	switch m.Type {
+	case MesgFileID:
+		if a.FileID == nil {
+			a.FileID = m
+		}
+	case MesgActivity:
+		a.Activity = m
+	case MesgSession:
+		a.Sessions = append(a.Sessions, m)
+	case MesgLap:
+		a.Laps = append(a.Laps, m)
+	case MesgRecord:
+		a.Records = append(a.Records, m)
+	case MesgEvent:
+		a.Events = append(a.Events, m)
+	case MesgDeviceInfo:
+		a.DeviceInfos = append(a.DeviceInfos, m)
+	case MesgLength:
+		a.Lengths = append(a.Lengths, m)
+	}
+}
+
+// StartTime returns the start time of the first session, falling back to
+// the time of the first record. It is zero if neither is known.
+func (a *Activity) StartTime() time.Time {
+	for _, s := range a.Sessions {
+		if start, ok := s.Timestamp("start_time"); ok {
+			return start
+		}
+	}
+	for _, r := range a.Records {
+		if !r.Time.IsZero() {
+			return r.Time
+		}
+	}
+	return time.Time{}
+}
+
+// UTCOffset returns the offset of the device's local time from UTC, derived
+// from the local_timestamp the activity message carries alongside its
+// timestamp. Other messages with both, such as monitoring_info, are used if
+// the activity message has none. It returns false if no plausible offset is
+// found.
+func (a *Activity) UTCOffset() (time.Duration, bool) {
+	if a.Activity != nil {
+		if offset, ok := utcOffset(a.Activity); ok {
+			return offset, true
+		}
+	}
+
+	for _, m := range a.Messages {
+		if offset, ok := utcOffset(m); ok {
+			return offset, true
+		}
+	}
+	return 0, false
+}
+
+// utcOffset returns the difference between a message's local_timestamp and
+// timestamp, rounded to the nearest quarter hour.
+func utcOffset(m *Message) (time.Duration, bool) {
+	local, lok := m.Float("local_timestamp")
+	utc, uok := m.Float("timestamp")
+	if !lok || !uok {
+		return 0, false
+	}
+
+	quarters := math.Round((local - utc) / (15 * 60))
+
+	// Time zones run from UTC-12 to UTC+14
+	if quarters < -12*4 || quarters > 14*4 {
+		return 0, false
+	}
+	return time.Duration(quarters) * 15 * time.Minute, true
+}
+
+// Location returns a fixed time zone for the device's UTC offset, or UTC if
+// it is not known.
+func (a *Activity) Location() *time.Location {
+	offset, ok := a.UTCOffset()
+	if !ok {
+		return time.UTC
+	}
+	return time.FixedZone("", int(offset/time.Second))
+}
+
+// LocalTime returns t in the device's local time.
+func (a *Activity) LocalTime(t time.Time) time.Time {
+	return t.In(a.Location())
+}
+
+// Localize sets the location of every message's Time to the device's local
+// time. The instants they represent do not change.
+func (a *Activity) Localize() {
+	loc := a.Location()
+	for _, m := range a.Messages {
+		if !m.Time.IsZero() {
+			m.Time = m.Time.In(loc)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package gofit
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func readActivity(t *testing.T, path string) *Activity {
+	f, ferr := os.Open(path)
+	if ferr != nil {
+		t.Fatalf("%s\n", ferr)
+	}
+	defer f.Close()
+
+	a, err := ReadActivity(f)
+	if err != nil {
+		t.Fatalf("%s: %s\n", path, err)
+	}
+	return a
+}
+
+func TestReadActivity(t *testing.T) {
+	a := readActivity(t, "testfiles/test2.fit")
+
+	if a.FileID == nil || a.Activity == nil || len(a.Sessions) != 1 || len(a.Laps) != 1 {
+		t.Fatalf("missing messages")
+	}
+	if len(a.Records) != 1309 || len(a.Events) != 3 || len(a.DeviceInfos) != 10 {
+		t.Errorf("got %d records, %d events, %d device infos", len(a.Records), len(a.Events), len(a.DeviceInfos))
+	}
+	if a.StartTime().Unix() != 1431558100 {
+		t.Errorf("got start time %s", a.StartTime())
+	}
+}
+
+func TestActivityTimeZone(t *testing.T) {
+	for path, want := range map[string]time.Duration{
+		"testfiles/test.fit":    2 * time.Hour,
+		"testfiles/fit2-2.fit":  2 * time.Hour,
+		"testfiles/devdata.fit": 2 * time.Hour,
+	} {
+		a := readActivity(t, path)
+
+		offset, ok := a.UTCOffset()
+		if !ok || offset != want {
+			t.Errorf("%s: got offset %s, want %s", path, offset, want)
+		}
+
+		start := a.LocalTime(a.StartTime())
+		if _, zoneOffset := start.Zone(); time.Duration(zoneOffset)*time.Second != want {
+			t.Errorf("%s: got local start %s", path, start)
+		}
+		if !start.Equal(a.StartTime()) {
+			t.Errorf("%s: local time should be the same instant", path)
+		}
+	}
+
+	// This device wrote a local timestamp that cannot be an offset from UTC
+	a := readActivity(t, "testfiles/test2.fit")
+	if _, ok := a.UTCOffset(); ok {
+		t.Errorf("expected no offset")
+	}
+	if a.Location() != time.UTC {
+		t.Errorf("expected UTC")
+	}
+}
+
+func TestActivityLocalize(t *testing.T) {
+	a := readActivity(t, "testfiles/test.fit")
+	first := a.Records[0].Time
+
+	a.Localize()
+
+	local := a.Records[0].Time
+	if !local.Equal(first) || local.Hour() != (first.Hour()+2)%24 {
+		t.Errorf("got %s for %s", local, first)
+	}
+}
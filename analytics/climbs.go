@@ -0,0 +1,265 @@
+package analytics
+
+import (
+	"math"
+	"time"
+
+	"github.com/kcfwpi/gofit"
+)
+
+// ElevationPoint is a point of an activity's elevation profile.
+type ElevationPoint struct {
+	Time     time.Time
+	Distance float64
+	Altitude float64
+}
+
+// ElevationProfile returns the altitude and distance of each record that
+// has both, preferring enhanced_altitude to altitude.
+func ElevationProfile(a *gofit.Activity) []ElevationPoint {
+	var points []ElevationPoint
+	for _, r := range a.Records {
+		alt, ok := r.Float("enhanced_altitude")
+		if !ok {
+			alt, ok = r.Float("altitude")
+		}
+		dist, dok := r.Float("distance")
+		if !ok || !dok {
+			continue
+		}
+		points = append(points, ElevationPoint{r.Time, dist, alt})
+	}
+	return points
+}
+
+// ElevationOptions configures elevation smoothing and climb detection.
+type ElevationOptions struct {
+	// Number of points in the centred moving average applied to altitudes
+	Smoothing int
+
+	// Change in altitude in meters needed to reverse direction. Smaller
+	// rises and falls are taken to be noise.
+	Hysteresis float64
+
+	// A climb must gain at least MinGain meters at an average grade of at
+	// least MinGrade percent
+	MinGain  float64
+	MinGrade float64
+}
+
+// DefaultElevationOptions come close to the total ascent devices report
+// from barometric altimeters.
+var DefaultElevationOptions = ElevationOptions{
+	Smoothing:  5,
+	Hysteresis: 3,
+	MinGain:    20,
+	MinGrade:   3,
+}
+
+// Smooth returns altitudes with a centred moving average over window
+// points applied. The window shrinks towards the ends.
+func Smooth(altitudes []float64, window int) []float64 {
+	smoothed := make([]float64, len(altitudes))
+	half := window / 2
+	for i := range altitudes {
+		lo, hi := i-half, i+half+1
+		if lo < 0 {
+			lo = 0
+		}
+		if hi > len(altitudes) {
+			hi = len(altitudes)
+		}
+
+		sum := 0.0
+		for _, alt := range altitudes[lo:hi] {
+			sum += alt
+		}
+		smoothed[i] = sum / float64(hi-lo)
+	}
+	return smoothed
+}
+
+// turningPoints returns the indexes of the first point of altitudes and the
+// alternating highs and lows that follow it, ignoring reversals smaller
+// than hysteresis.
+func turningPoints(altitudes []float64, hysteresis float64) []int {
+	if len(altitudes) == 0 {
+		return nil
+	}
+
+	points := []int{0}
+
+	// The candidate for the next turning point, and whether the profile is
+	// rising or falling towards it. Neither is known until it has moved by
+	// the hysteresis from the start.
+	extreme, dir := 0, 0
+
+	for i, alt := range altitudes {
+		switch {
+		case dir == 0:
+			if math.Abs(alt-altitudes[0]) >= hysteresis {
+				extreme, dir = i, 1
+				if alt < altitudes[0] {
+					dir = -1
+				}
+			}
+		case dir > 0 && alt > altitudes[extreme], dir < 0 && alt < altitudes[extreme]:
+			extreme = i
+		case math.Abs(alt-altitudes[extreme]) >= hysteresis:
+			points = append(points, extreme)
+			extreme, dir = i, -dir
+		}
+	}
+
+	if dir != 0 {
+		points = append(points, extreme)
+	}
+	return points
+}
+
+// Elevation returns the total ascent and descent of an activity, in
+// meters, after smoothing.
+func Elevation(a *gofit.Activity, opts ElevationOptions) (ascent, descent float64) {
+	profile := ElevationProfile(a)
+	altitudes := make([]float64, len(profile))
+	for i, p := range profile {
+		altitudes[i] = p.Altitude
+	}
+	return Gain(Smooth(altitudes, opts.Smoothing), opts.Hysteresis)
+}
+
+// Gain returns the total ascent and descent of altitudes, ignoring rises
+// and falls smaller than hysteresis.
+func Gain(altitudes []float64, hysteresis float64) (ascent, descent float64) {
+	points := turningPoints(altitudes, hysteresis)
+	for i := 1; i < len(points); i++ {
+		if d := altitudes[points[i]] - altitudes[points[i-1]]; d > 0 {
+			ascent += d
+		} else {
+			descent -= d
+		}
+	}
+	return ascent, descent
+}
+
+// Climb is a sustained rise in an activity's elevation profile.
+type Climb struct {
+	Start, End ElevationPoint
+
+	// Length in meters and the altitude gained over it
+	Length float64
+	Gain   float64
+
+	// Average grade in percent
+	Grade float64
+
+	// Velocità ascensionale media: the rate of ascent in meters an hour
+	VAM float64
+
+	Category ClimbCategory
+}
+
+// Duration returns the time taken to ride the climb.
+func (c Climb) Duration() time.Duration {
+	return c.End.Time.Sub(c.Start.Time)
+}
+
+// ClimbCategory grades a climb by its difficulty, from 4 up to hors
+// catégorie.
+type ClimbCategory int
+
+const (
+	Uncategorized ClimbCategory = iota
+	Category4
+	Category3
+	Category2
+	Category1
+	HorsCategorie
+)
+
+// Minimum product of length in meters and grade in percent for each
+// category, from the hardest
+var climbCategories = []struct {
+	score    float64
+	category ClimbCategory
+}{
+	{80000, HorsCategorie},
+	{64000, Category1},
+	{32000, Category2},
+	{16000, Category3},
+	{8000, Category4},
+}
+
+func categorize(length, grade float64) ClimbCategory {
+	score := length * grade
+	for _, c := range climbCategories {
+		if score >= c.score {
+			return c.category
+		}
+	}
+	return Uncategorized
+}
+
+// String returns the usual name of the category.
+func (c ClimbCategory) String() string {
+	switch c {
+	case Category4:
+		return "4"
+	case Category3:
+		return "3"
+	case Category2:
+		return "2"
+	case Category1:
+		return "1"
+	case HorsCategorie:
+		return "HC"
+	}
+	return ""
+}
+
+// Climbs finds the climbs of an activity: each rise of the smoothed
+// profile, between turning points found with the configured hysteresis,
+// that gains enough altitude at a steep enough average grade.
+func Climbs(a *gofit.Activity, opts ElevationOptions) []Climb {
+	profile := ElevationProfile(a)
+
+	altitudes := make([]float64, len(profile))
+	for i, p := range profile {
+		altitudes[i] = p.Altitude
+	}
+	altitudes = Smooth(altitudes, opts.Smoothing)
+
+	var climbs []Climb
+	points := turningPoints(altitudes, opts.Hysteresis)
+	for i := 1; i < len(points); i++ {
+		lo, hi := points[i-1], points[i]
+
+		gain := altitudes[hi] - altitudes[lo]
+		length := profile[hi].Distance - profile[lo].Distance
+		if gain < opts.MinGain || length <= 0 {
+			continue
+		}
+
+		grade := gain / length * 100
+		if grade < opts.MinGrade {
+			continue
+		}
+
+		start, end := profile[lo], profile[hi]
+		start.Altitude, end.Altitude = altitudes[lo], altitudes[hi]
+
+		climb := Climb{
+			Start:    start,
+			End:      end,
+			Length:   length,
+			Gain:     gain,
+			Grade:    grade,
+			Category: categorize(length, grade),
+		}
+		if hours := climb.Duration().Hours(); hours > 0 {
+			climb.VAM = gain / hours
+		}
+		climbs = append(climbs, climb)
+	}
+	return climbs
+}
@@ -0,0 +1,88 @@
+package analytics
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestGain(t *testing.T) {
+	// Noise of a meter either way on a 10m rise and 4m fall
+	altitudes := []float64{100, 101, 100, 101, 105, 104, 110, 109, 110, 106, 107, 106}
+
+	if points := turningPoints(altitudes, 3); !reflect.DeepEqual(points, []int{0, 6, 9}) {
+		t.Errorf("got turning points %v", points)
+	}
+	if ascent, descent := Gain(altitudes, 3); ascent != 10 || descent != 4 {
+		t.Errorf("got ascent %f, descent %f", ascent, descent)
+	}
+	if ascent, _ := Gain(altitudes, 0); ascent != 14 {
+		t.Errorf("got raw ascent %f", ascent)
+	}
+	if ascent, descent := Gain(altitudes[:3], 3); ascent != 0 || descent != 0 {
+		t.Errorf("noise should not count, got %f and %f", ascent, descent)
+	}
+
+	if smoothed := Smooth([]float64{0, 3, 0, 3}, 3); !reflect.DeepEqual(smoothed, []float64{1.5, 1, 2, 1.5}) {
+		t.Errorf("got smoothed %v", smoothed)
+	}
+}
+
+func TestElevationMatchesDevice(t *testing.T) {
+	for _, path := range []string{
+		"../testfiles/test.fit",
+		"../testfiles/test2.fit",
+		"../testfiles/fit2.fit",
+		"../testfiles/devdata.fit",
+	} {
+		a := readActivity(t, path)
+
+		want, _ := a.Sessions[0].Float("total_ascent")
+		ascent, _ := Elevation(a, DefaultElevationOptions)
+		if math.Abs(ascent-want) > 0.15*want {
+			t.Errorf("%s: got ascent %f, device reports %f", path, ascent, want)
+		}
+
+		raw, _ := Elevation(a, ElevationOptions{Smoothing: 1})
+		if raw <= ascent {
+			t.Errorf("%s: smoothing should reduce the noise, got %f raw and %f smoothed", path, raw, ascent)
+		}
+	}
+}
+
+func TestClimbs(t *testing.T) {
+	a := readActivity(t, "../testfiles/test.fit")
+
+	opts := DefaultElevationOptions
+	climbs := Climbs(a, opts)
+
+	ascent, _ := Elevation(a, opts)
+	total, hardest := 0.0, Uncategorized
+	for _, c := range climbs {
+		if c.Gain < opts.MinGain || c.Grade < opts.MinGrade {
+			t.Errorf("climb of %fm at %f%% should not count", c.Gain, c.Grade)
+		}
+		if math.Abs(c.End.Altitude-c.Start.Altitude-c.Gain) > 1e-9 || c.Length != c.End.Distance-c.Start.Distance {
+			t.Errorf("climb does not match its end points: %+v", c)
+		}
+		if c.VAM <= 0 || c.VAM > 2000 {
+			t.Errorf("got VAM %f", c.VAM)
+		}
+
+		total += c.Gain
+		if c.Category > hardest {
+			hardest = c.Category
+		}
+	}
+
+	if total > ascent {
+		t.Errorf("climbs gain %f of a total ascent of %f", total, ascent)
+	}
+	if hardest != Category1 {
+		t.Errorf("hardest climb is category %s", hardest)
+	}
+
+	if c := categorize(10000, 8); c != HorsCategorie || c.String() != "HC" {
+		t.Errorf("got category %s", c)
+	}
+}
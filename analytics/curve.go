@@ -0,0 +1,155 @@
+package analytics
+
+import (
+	"math"
+	"time"
+
+	"github.com/kcfwpi/gofit"
+)
+
+// Curves hold every second up to this duration, and steps of
+// 1/curveResolution of the duration beyond it
+const curveResolution = 100
+
+// Effort is the best average of a series over a duration.
+type Effort struct {
+	Duration time.Duration
+	Value    float64
+
+	// When the effort started, and when its last sample ended
+	Start time.Time
+	End   time.Time
+}
+
+// Curve is a mean-maximal curve: the best effort for each duration, in
+// order of increasing duration. Durations with no window free of missing
+// samples are left out.
+//
+// Efforts holds every second up to 100 seconds and then steps of 1% of the
+// duration, which is enough to plot the curve. Best computes the effort for
+// any other duration from the samples the curve keeps.
+type Curve struct {
+	Efforts []Effort
+
+	// Prefix sums of the samples and of the number missing, and the times
+	// of the samples
+	sums    []float64
+	missing []int
+	times   []time.Time
+}
+
+// MeanMax computes the mean-maximal curve of samples taken once a second
+// at times, from 1 second up to the length of the series. Windows that
+// include a missing sample, NaN, are not considered.
+//
+// Finding the best window for a duration takes a single pass over the
+// samples, so rather than every duration the curve's Efforts hold a grid of
+// them. For a ride of n seconds this is O(n log n) rather than O(n²).
+func MeanMax(samples []float64, times []time.Time) Curve {
+	n := len(samples)
+
+	c := Curve{
+		sums:    make([]float64, n+1),
+		missing: make([]int, n+1),
+		times:   times,
+	}
+	for i, v := range samples {
+		c.sums[i+1], c.missing[i+1] = c.sums[i], c.missing[i]
+		if math.IsNaN(v) {
+			c.missing[i+1]++
+		} else {
+			c.sums[i+1] += v
+		}
+	}
+
+	for _, d := range curveDurations(n) {
+		if e, ok := c.effort(d); ok {
+			c.Efforts = append(c.Efforts, e)
+		}
+	}
+	return c
+}
+
+// effort finds the best window of d seconds, if any is free of missing
+// samples.
+func (c Curve) effort(d int) (Effort, bool) {
+	n := len(c.sums) - 1
+
+	best, start := math.Inf(-1), -1
+	for i := 0; i+d <= n; i++ {
+		if c.missing[i+d] != c.missing[i] {
+			continue
+		}
+		if sum := c.sums[i+d] - c.sums[i]; sum > best {
+			best, start = sum, i
+		}
+	}
+	if start < 0 {
+		return Effort{}, false
+	}
+
+	return Effort{
+		Duration: time.Duration(d) * time.Second,
+		Value:    best / float64(d),
+		Start:    c.times[start],
+		End:      c.times[start+d-1].Add(time.Second),
+	}, true
+}
+
+// curveDurations returns the durations, in seconds, a curve over n samples
+// is computed for.
+func curveDurations(n int) []int {
+	var durations []int
+	for d := 1; d <= n; {
+		durations = append(durations, d)
+
+		step := d / curveResolution
+		if step < 1 {
+			step = 1
+		}
+		d += step
+	}
+
+	if len(durations) > 0 && durations[len(durations)-1] != n {
+		durations = append(durations, n)
+	}
+	return durations
+}
+
+// Best returns the best effort over d, rounded up to a whole second. It is
+// computed exactly whether or not d is one of the curve's Efforts, taking a
+// pass over the samples when it is not. It returns false if d is longer
+// than the series or every window of d includes a missing sample.
+func (c Curve) Best(d time.Duration) (Effort, bool) {
+	seconds := int((d + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	if seconds > len(c.sums)-1 {
+		return Effort{}, false
+	}
+
+	for _, e := range c.Efforts {
+		if e.Duration == time.Duration(seconds)*time.Second {
+			return e, true
+		}
+	}
+	return c.effort(seconds)
+}
+
+// PowerCurve returns the mean-maximal power curve of an activity.
+func PowerCurve(a *gofit.Activity) Curve {
+	return MeanMax(Power(a), SampleTimes(a))
+}
+
+// SpeedCurve returns the best average speed in m/s of an activity for each
+// duration. The units package converts speeds to paces.
+func SpeedCurve(a *gofit.Activity) Curve {
+	return MeanMax(Speed(a), SampleTimes(a))
+}
+
+// HeartRateCurve returns the best average heart rate of an activity for
+// each duration.
+func HeartRateCurve(a *gofit.Activity) Curve {
+	return MeanMax(HeartRate(a), SampleTimes(a))
+}
@@ -0,0 +1,167 @@
+package analytics
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestMeanMax(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	start := time.Unix(1431558100, 0)
+	samples := make([]float64, 1000)
+	times := make([]time.Time, len(samples))
+	for i := range samples {
+		samples[i] = rng.Float64() * 400
+		times[i] = start.Add(time.Duration(i) * time.Second)
+	}
+	samples[500] = math.NaN()
+
+	curve := MeanMax(samples, times)
+	if len(curve.Efforts) == 0 || curve.Efforts[0].Duration != time.Second {
+		t.Fatalf("got curve %v", curve.Efforts)
+	}
+
+	for _, e := range curve.Efforts {
+		d := int(e.Duration / time.Second)
+
+		// Compare with a brute force search of the windows avoiding the gap
+		best := 0.0
+		for s := 0; s+d <= len(samples); s++ {
+			sum := 0.0
+			for _, v := range samples[s : s+d] {
+				sum += v
+			}
+			if sum/float64(d) > best {
+				best = sum / float64(d)
+			}
+		}
+
+		if math.Abs(e.Value-best) > 1e-9 {
+			t.Errorf("%s: got %f, want %f", e.Duration, e.Value, best)
+		}
+		if e.End.Sub(e.Start) != e.Duration {
+			t.Errorf("%s: effort runs from %s to %s", e.Duration, e.Start, e.End)
+		}
+	}
+
+	// Windows over the gap are skipped, so the longest effort is either side
+	if last := curve.Efforts[len(curve.Efforts)-1]; last.Duration > 500*time.Second || last.Duration < 495*time.Second {
+		t.Errorf("longest effort is %s", last.Duration)
+	}
+}
+
+func TestCurveBest(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+
+	start := time.Unix(1431558100, 0)
+	samples := make([]float64, 600)
+	times := make([]time.Time, len(samples))
+	for i := range samples {
+		samples[i] = rng.Float64() * 400
+		times[i] = start.Add(time.Duration(i) * time.Second)
+	}
+
+	curve := MeanMax(samples, times)
+
+	// 201 seconds falls between the 200 and 202 second steps of the curve
+	for _, e := range curve.Efforts {
+		if e.Duration == 201*time.Second {
+			t.Fatalf("201 seconds is on the curve")
+		}
+	}
+
+	for _, d := range []time.Duration{201 * time.Second, 200*time.Second + time.Millisecond, 337 * time.Second} {
+		e, ok := curve.Best(d)
+		seconds := int((d + time.Second - 1) / time.Second)
+		if !ok || e.Duration != time.Duration(seconds)*time.Second {
+			t.Errorf("%s: got effort over %s", d, e.Duration)
+			continue
+		}
+
+		best, at := 0.0, 0
+		for s := 0; s+seconds <= len(samples); s++ {
+			sum := 0.0
+			for _, v := range samples[s : s+seconds] {
+				sum += v
+			}
+			if sum/float64(seconds) > best {
+				best, at = sum/float64(seconds), s
+			}
+		}
+		if math.Abs(e.Value-best) > 1e-9 || !e.Start.Equal(times[at]) {
+			t.Errorf("%s: got %f from %s, want %f from %s", d, e.Value, e.Start, best, times[at])
+		}
+	}
+
+	if _, ok := curve.Best(601 * time.Second); ok {
+		t.Errorf("got an effort longer than the series")
+	}
+}
+
+func TestCurveDurations(t *testing.T) {
+	durations := curveDurations(18000)
+	if len(durations) > 1000 || durations[99] != 100 || durations[len(durations)-1] != 18000 {
+		t.Errorf("got %d durations", len(durations))
+	}
+	for i := 1; i < len(durations); i++ {
+		if step := durations[i] - durations[i-1]; step < 1 || step > durations[i-1]/curveResolution+1 {
+			t.Errorf("step of %d after %d", step, durations[i-1])
+		}
+	}
+}
+
+func TestPowerCurve(t *testing.T) {
+	a := readActivity(t, "../testfiles/test2.fit")
+	session := a.Sessions[0]
+
+	curve := PowerCurve(a)
+
+	maxPower, _ := session.Float("max_power")
+	if peak, ok := curve.Best(time.Second); !ok || peak.Value != maxPower {
+		t.Errorf("got peak power %f, device reports %f", peak.Value, maxPower)
+	}
+
+	avgPower, _ := session.Float("avg_power")
+	whole := curve.Efforts[len(curve.Efforts)-1]
+	if whole.Duration != a.Timer(0).TimerTime().Truncate(time.Second) || math.Abs(whole.Value-avgPower) > 2 {
+		t.Errorf("got %f over %s, device reports %f", whole.Value, whole.Duration, avgPower)
+	}
+
+	twenty, ok := curve.Best(20 * time.Minute)
+	if !ok || twenty.Duration < 20*time.Minute || twenty.Value < whole.Value {
+		t.Errorf("got 20 minute effort %+v", twenty)
+	}
+	if twenty.Start.Before(a.StartTime()) {
+		t.Errorf("effort starts before the activity")
+	}
+}
+
+func TestSpeedCurve(t *testing.T) {
+	a := readActivity(t, "../testfiles/test.fit")
+
+	curve := SpeedCurve(a)
+	maxSpeed, _ := a.Sessions[0].Float("enhanced_max_speed")
+	// Records within the same second are resampled to the last of them
+	if peak, ok := curve.Best(time.Second); !ok || math.Abs(peak.Value-maxSpeed) > 0.01*maxSpeed {
+		t.Errorf("got peak speed %f, device reports %f", peak.Value, maxSpeed)
+	}
+	if hr := HeartRateCurve(a); len(hr.Efforts) == 0 {
+		t.Errorf("no heart rate curve")
+	}
+}
+
+func BenchmarkPowerCurve(b *testing.B) {
+	// Five hours of power
+	samples := make([]float64, 5*3600)
+	times := make([]time.Time, len(samples))
+	for i := range samples {
+		samples[i] = float64(i % 400)
+	}
+
+	for i := 0; i < b.N; i++ {
+		MeanMax(samples, times)
+	}
+}
@@ -0,0 +1,104 @@
+package analytics
+
+import (
+	"time"
+
+	"github.com/kcfwpi/gofit"
+)
+
+// Dynamics summarises running dynamics and pedalling balance over a span
+// of records. Each is the average of the records with a valid, non-zero
+// value, or 0 if there are none. Sensors report zeros while standing still,
+// which devices leave out of their own averages too.
+type Dynamics struct {
+	VerticalOscillation float64 // mm
+	VerticalRatio       float64 // percent
+	StanceTime          float64 // ms
+	StanceTimePercent   float64 // percent
+	StanceTimeBalance   float64 // percent, left
+	StepLength          float64 // mm
+
+	// Average share of power from the right leg, from records whose
+	// balance is known to be the right's
+	RightBalance float64
+}
+
+// Record fields averaged into Dynamics, in the order of its fields
+var dynamicsFields = []string{
+	"vertical_oscillation",
+	"vertical_ratio",
+	"stance_time",
+	"stance_time_percent",
+	"stance_time_balance",
+	"step_length",
+}
+
+// RecordDynamics summarises the records given.
+func RecordDynamics(records []*gofit.Message) Dynamics {
+	var sums, counts [7]float64
+	for _, r := range records {
+		for i, name := range dynamicsFields {
+			if v, ok := r.Float(name); ok && v > 0 {
+				sums[i] += v
+				counts[i]++
+			}
+		}
+
+		if b, ok := r.Balance("left_right_balance"); ok && b.Right {
+			sums[6] += b.Percent
+			counts[6]++
+		}
+	}
+
+	var avg [7]float64
+	for i := range sums {
+		if counts[i] > 0 {
+			avg[i] = sums[i] / counts[i]
+		}
+	}
+
+	return Dynamics{
+		VerticalOscillation: avg[0],
+		VerticalRatio:       avg[1],
+		StanceTime:          avg[2],
+		StanceTimePercent:   avg[3],
+		StanceTimeBalance:   avg[4],
+		StepLength:          avg[5],
+		RightBalance:        avg[6],
+	}
+}
+
+// LapDynamics summarises the records of each lap of an activity, in lap
+// order. A lap's records are those from its start_time up to its timestamp.
+func LapDynamics(a *gofit.Activity) []Dynamics {
+	laps := make([]Dynamics, len(a.Laps))
+	for i, lap := range a.Laps {
+		laps[i] = summaryDynamics(a, lap)
+	}
+	return laps
+}
+
+// SessionDynamics summarises the records of each session of an activity.
+func SessionDynamics(a *gofit.Activity) []Dynamics {
+	sessions := make([]Dynamics, len(a.Sessions))
+	for i, s := range a.Sessions {
+		sessions[i] = summaryDynamics(a, s)
+	}
+	return sessions
+}
+
+// summaryDynamics summarises the records during a lap or session.
+func summaryDynamics(a *gofit.Activity, m *gofit.Message) Dynamics {
+	start, _ := m.Timestamp("start_time")
+	return RecordDynamics(recordsBetween(a, start, m.Time))
+}
+
+func recordsBetween(a *gofit.Activity, start, end time.Time) []*gofit.Message {
+	var records []*gofit.Message
+	for _, r := range a.Records {
+		if !r.Time.Before(start) && !r.Time.After(end) {
+			records = append(records, r)
+		}
+	}
+	return records
+}
@@ -0,0 +1,58 @@
+package analytics
+
+import (
+	"math"
+	"testing"
+
+	"github.com/kcfwpi/gofit"
+)
+
+func TestDynamicsMatchesDevice(t *testing.T) {
+	for _, path := range []string{"../testfiles/devdata.fit", "../testfiles/fit2.fit"} {
+		a := readActivity(t, path)
+
+		sessions := SessionDynamics(a)
+		laps := LapDynamics(a)
+
+		for i, d := range append(sessions, laps...) {
+			m := a.Sessions[0]
+			if i >= len(sessions) {
+				m = a.Laps[i-len(sessions)]
+			}
+
+			// Some devices work out the average step length from the
+			// distance and step count instead, which differs more
+			for _, c := range []struct {
+				name      string
+				got       float64
+				tolerance float64
+			}{
+				{"avg_vertical_oscillation", d.VerticalOscillation, 0.05},
+				{"avg_vertical_ratio", d.VerticalRatio, 0.05},
+				{"avg_stance_time", d.StanceTime, 0.05},
+				{"avg_stance_time_percent", d.StanceTimePercent, 0.05},
+				{"avg_stance_time_balance", d.StanceTimeBalance, 0.05},
+				{"avg_step_length", d.StepLength, 0.15},
+			} {
+				want, _ := m.Float(c.name)
+				if math.Abs(c.got-want) > c.tolerance*want {
+					t.Errorf("%s %s %d: got %f, device reports %f", path, m.Name, i, c.got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestRecordBalance(t *testing.T) {
+	record := func(balance byte) *gofit.Message {
+		return &gofit.Message{Type: gofit.MesgRecord, Fields: []gofit.Field{
+			{Num: 30, Name: "left_right_balance", Type: gofit.TypeUint8, Value: float64(balance)},
+		}}
+	}
+
+	// The balance without a side is left out
+	d := RecordDynamics([]*gofit.Message{record(0x80 | 52), record(0x80 | 54), record(40)})
+	if d.RightBalance != 53 {
+		t.Errorf("got right balance %f", d.RightBalance)
+	}
+}
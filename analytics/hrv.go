@@ -0,0 +1,251 @@
+package analytics
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/kcfwpi/gofit"
+)
+
+// RRInterval is the time between two heartbeats, ending at Time.
+type RRInterval struct {
+	Time     time.Time
+	Interval float64 // seconds
+}
+
+// RR intervals outside this range, 30 to 220 bpm, cannot be real beats
+const (
+	minRRInterval = 60.0 / 220
+	maxRRInterval = 60.0 / 30
+)
+
+// RRIntervals extracts the R-R intervals an activity's hrv messages hold.
+// The messages carry no timestamps, so the beats are timed by adding up the
+// intervals from the time of the message before the first of them. Where
+// beats have been lost and the sum falls behind the messages around it, it
+// is brought forward to the time of the preceding message.
+func RRIntervals(a *gofit.Activity) []RRInterval {
+	var rr []RRInterval
+
+	var now, last time.Time
+	for _, m := range a.Messages {
+		if m.Type != gofit.MesgHrv {
+			if !m.Time.IsZero() {
+				last = m.Time
+			}
+			continue
+		}
+
+		if now.Before(last.Add(-time.Second)) {
+			now = last
+		}
+
+		for _, interval := range m.Floats("time") {
+			if math.IsNaN(interval) {
+				continue
+			}
+			now = now.Add(time.Duration(interval * float64(time.Second)))
+			rr = append(rr, RRInterval{now, interval})
+		}
+	}
+	return rr
+}
+
+// FilterRR removes artifacts from R-R intervals: intervals that could not
+// be a heartbeat, and those differing by more than the fraction threshold
+// from the median of the intervals around them. Missed or extra beats
+// detected by the strap show up as such jumps. Typical thresholds are 0.2
+// to 0.25.
+func FilterRR(rr []RRInterval, threshold float64) []RRInterval {
+	const radius = 5
+
+	var filtered []RRInterval
+	window := make([]float64, 0, 2*radius+1)
+	for i, r := range rr {
+		if r.Interval < minRRInterval || r.Interval > maxRRInterval {
+			continue
+		}
+
+		window = window[:0]
+		for j := i - radius; j <= i+radius; j++ {
+			if j >= 0 && j < len(rr) && j != i {
+				window = append(window, rr[j].Interval)
+			}
+		}
+		if len(window) > 0 {
+			sort.Float64s(window)
+			median := window[len(window)/2]
+			if math.Abs(r.Interval-median) > threshold*median {
+				continue
+			}
+		}
+
+		filtered = append(filtered, r)
+	}
+	return filtered
+}
+
+func intervals(rr []RRInterval) []float64 {
+	values := make([]float64, len(rr))
+	for i, r := range rr {
+		values[i] = r.Interval
+	}
+	return values
+}
+
+// RMSSD returns the root mean square of the successive differences of R-R
+// intervals, in milliseconds.
+func RMSSD(rr []RRInterval) float64 {
+	if len(rr) < 2 {
+		return 0
+	}
+
+	sum := 0.0
+	for i := 1; i < len(rr); i++ {
+		d := (rr[i].Interval - rr[i-1].Interval) * 1000
+		sum += d * d
+	}
+	return math.Sqrt(sum / float64(len(rr)-1))
+}
+
+// SDNN returns the standard deviation of R-R intervals, in milliseconds.
+func SDNN(rr []RRInterval) float64 {
+	if len(rr) < 2 {
+		return 0
+	}
+
+	values := intervals(rr)
+	mean := 0.0
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+
+	sum := 0.0
+	for _, v := range values {
+		sum += (v - mean) * (v - mean) * 1e6
+	}
+	return math.Sqrt(sum / float64(len(values)-1))
+}
+
+// Box sizes, in beats, over which DFA alpha 1 measures short term scaling
+const (
+	minDFABox = 4
+	maxDFABox = 16
+)
+
+// DFAAlpha1 returns the short term scaling exponent of detrended
+// fluctuation analysis of R-R intervals, over boxes of 4 to 16 beats. It is
+// around 1 at rest, 0.75 at the aerobic threshold and 0.5 at the anaerobic
+// threshold. It returns NaN if there are too few intervals.
+func DFAAlpha1(rr []RRInterval) float64 {
+	values := intervals(rr)
+	if len(values) < 2*maxDFABox {
+		return math.NaN()
+	}
+
+	mean := 0.0
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+
+	// Integrate the series
+	profile := make([]float64, len(values))
+	sum := 0.0
+	for i, v := range values {
+		sum += v - mean
+		profile[i] = sum
+	}
+
+	var logN, logF []float64
+	for n := minDFABox; n <= maxDFABox; n++ {
+		f := fluctuation(profile, n)
+		if f <= 0 {
+			continue
+		}
+		logN = append(logN, math.Log(float64(n)))
+		logF = append(logF, math.Log(f))
+	}
+
+	slope, _ := fitLine(logN, logF)
+	return slope
+}
+
+// fluctuation returns the root mean square deviation of profile from the
+// straight lines fitted to each of its boxes of n points.
+func fluctuation(profile []float64, n int) float64 {
+	x := make([]float64, n)
+	for i := range x {
+		x[i] = float64(i)
+	}
+
+	sum, count := 0.0, 0
+	for start := 0; start+n <= len(profile); start += n {
+		box := profile[start : start+n]
+
+		slope, intercept := fitLine(x, box)
+		for i, y := range box {
+			d := y - (slope*x[i] + intercept)
+			sum += d * d
+		}
+		count += n
+	}
+	return math.Sqrt(sum / float64(count))
+}
+
+// fitLine fits y = slope*x + intercept by least squares.
+func fitLine(x, y []float64) (slope, intercept float64) {
+	n := float64(len(x))
+
+	var sx, sy, sxx, sxy float64
+	for i := range x {
+		sx += x[i]
+		sy += y[i]
+		sxx += x[i] * x[i]
+		sxy += x[i] * y[i]
+	}
+
+	slope = (n*sxy - sx*sy) / (n*sxx - sx*sx)
+	intercept = (sy - slope*sx) / n
+	return slope, intercept
+}
+
+// HRVPoint holds heart rate variability metrics over a window of R-R
+// intervals ending at Time.
+type HRVPoint struct {
+	Time      time.Time
+	RMSSD     float64
+	SDNN      float64
+	DFAAlpha1 float64
+}
+
+// HRVSeries computes the metrics over windows of R-R intervals of length
+// window, one every step. Two minute windows are usual for DFA alpha 1.
+// Windows with too few intervals for DFA have a NaN DFAAlpha1.
+func HRVSeries(rr []RRInterval, window, step time.Duration) []HRVPoint {
+	if len(rr) == 0 || step <= 0 {
+		return nil
+	}
+
+	var points []HRVPoint
+	start := 0
+	for end := rr[0].Time.Add(window); end.Before(rr[len(rr)-1].Time.Add(step)); end = end.Add(step) {
+		for start < len(rr) && !rr[start].Time.After(end.Add(-window)) {
+			start++
+		}
+		stop := sort.Search(len(rr), func(i int) bool {
+			return rr[i].Time.After(end)
+		})
+
+		w := rr[start:stop]
+		points = append(points, HRVPoint{
+			Time:      end,
+			RMSSD:     RMSSD(w),
+			SDNN:      SDNN(w),
+			DFAAlpha1: DFAAlpha1(w),
+		})
+	}
+	return points
+}
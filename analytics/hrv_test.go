@@ -0,0 +1,124 @@
+package analytics
+
+import (
+	"math"
+	"math/rand"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/kcfwpi/gofit"
+)
+
+func rrSeries(intervals ...float64) []RRInterval {
+	now := time.Unix(1443802195, 0)
+
+	rr := make([]RRInterval, len(intervals))
+	for i, v := range intervals {
+		now = now.Add(time.Duration(v * float64(time.Second)))
+		rr[i] = RRInterval{now, v}
+	}
+	return rr
+}
+
+func TestRRMetrics(t *testing.T) {
+	steady := rrSeries(0.8, 0.8, 0.8, 0.8)
+	if RMSSD(steady) != 0 || SDNN(steady) != 0 {
+		t.Errorf("got RMSSD %f, SDNN %f for a steady heart", RMSSD(steady), SDNN(steady))
+	}
+
+	alternating := rrSeries(0.8, 0.9, 0.8, 0.9, 0.8)
+	if rmssd := RMSSD(alternating); math.Abs(rmssd-100) > 1e-9 {
+		t.Errorf("got RMSSD %f", rmssd)
+	}
+	if sdnn := SDNN(alternating); math.Abs(sdnn-54.772256) > 1e-6 {
+		t.Errorf("got SDNN %f", sdnn)
+	}
+}
+
+func TestDFAAlpha1(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	noise := make([]float64, 2000)
+	brown := make([]float64, len(noise))
+	walk := 0.0
+	for i := range noise {
+		noise[i] = 0.8 + rng.NormFloat64()*0.05
+		walk += rng.NormFloat64() * 0.005
+		brown[i] = 0.8 + walk
+	}
+
+	// Uncorrelated beats scale with 0.5 and a random walk with 1.5
+	if alpha := DFAAlpha1(rrSeries(noise...)); math.Abs(alpha-0.5) > 0.1 {
+		t.Errorf("got alpha 1 of %f for white noise", alpha)
+	}
+	if alpha := DFAAlpha1(rrSeries(brown...)); math.Abs(alpha-1.5) > 0.1 {
+		t.Errorf("got alpha 1 of %f for a random walk", alpha)
+	}
+	if alpha := DFAAlpha1(rrSeries(noise[:20]...)); !math.IsNaN(alpha) {
+		t.Errorf("too few beats should give NaN, got %f", alpha)
+	}
+
+	series := HRVSeries(rrSeries(noise...), 2*time.Minute, 30*time.Second)
+	if len(series) < 48 || len(series) > 52 {
+		t.Errorf("got %d points for 26 minutes", len(series))
+	}
+	for _, p := range series {
+		if math.Abs(p.DFAAlpha1-0.5) > 0.25 || p.RMSSD <= 0 {
+			t.Errorf("got %+v", p)
+			break
+		}
+	}
+}
+
+func TestFilterRR(t *testing.T) {
+	rr := rrSeries(0.8, 0.81, 0.79, 0.8, 1.6, 0.8, 0.82, 0.1, 0.8, 0.81, 3)
+
+	filtered := FilterRR(rr, 0.2)
+	if len(filtered) != len(rr)-3 {
+		t.Fatalf("got %d intervals after filtering", len(filtered))
+	}
+	for _, r := range filtered {
+		if r.Interval < 0.7 || r.Interval > 0.9 {
+			t.Errorf("artifact %f was kept", r.Interval)
+		}
+	}
+}
+
+func TestRRIntervals(t *testing.T) {
+	// The tail of this file is corrupt, but what is read of it is fine
+	f, err := os.Open("../testfiles/bad.fit")
+	if err != nil {
+		t.Fatalf("%s\n", err)
+	}
+	defer f.Close()
+	a, _ := gofit.ReadActivity(f)
+
+	rr := RRIntervals(a)
+	if len(rr) < 1700 {
+		t.Fatalf("got %d R-R intervals", len(rr))
+	}
+
+	// This strap drops beats, so the times are brought forward to keep pace
+	// with the records they are written between
+	for i := 1; i < len(rr); i++ {
+		if !rr[i].Time.After(rr[i-1].Time) {
+			t.Fatalf("beat %d at %s is not after the one before", i, rr[i].Time)
+		}
+	}
+	first, last := a.Records[0].Time, a.Records[len(a.Records)-1].Time
+	if d := rr[0].Time.Sub(first); d < -time.Second || d > time.Minute {
+		t.Errorf("first beat at %s, first record at %s", rr[0].Time, first)
+	}
+	if d := rr[len(rr)-1].Time.Sub(last); d < -time.Minute || d > 3*time.Second {
+		t.Errorf("last beat at %s, last record at %s", rr[len(rr)-1].Time, last)
+	}
+
+	filtered := FilterRR(rr, 0.2)
+	if len(filtered) < len(rr)*9/10 {
+		t.Errorf("filtering removed %d of %d intervals", len(rr)-len(filtered), len(rr))
+	}
+	if rmssd := RMSSD(filtered); rmssd <= 0 || rmssd > 200 {
+		t.Errorf("got RMSSD %f", rmssd)
+	}
+}
@@ -0,0 +1,89 @@
+package analytics
+
+import (
+	"math"
+	"time"
+
+	"github.com/kcfwpi/gofit"
+)
+
+// Normalized power uses a rolling average over this many seconds
+const normalizedPowerWindow = 30
+
+// Load summarises the training load of an activity.
+type Load struct {
+	// Functional threshold power the load is relative to
+	FTP float64
+
+	NormalizedPower float64
+	IntensityFactor float64
+	TrainingStress  float64
+
+	// Time the timer ran
+	Duration time.Duration
+}
+
+// TrainingLoad computes the normalized power, intensity factor and training
+// stress score of an activity for a rider with the given functional
+// threshold power, in watts. The duration is the time the timer ran,
+// including any gaps in the power data.
+func TrainingLoad(a *gofit.Activity, ftp float64) Load {
+	power := Power(a)
+	duration := time.Duration(len(power)) * time.Second
+
+	np := NormalizedPower(power)
+	return Load{
+		FTP:             ftp,
+		NormalizedPower: np,
+		IntensityFactor: IntensityFactor(np, ftp),
+		TrainingStress:  TSS(duration, np, ftp),
+		Duration:        duration,
+	}
+}
+
+// NormalizedPower returns the normalized power of power sampled once a
+// second: the fourth root of the mean of the fourth powers of its 30 second
+// rolling average. Missing samples, NaN, are skipped so that the average
+// rolls over the gaps. It is 0 if there are fewer than 30 valid samples.
+func NormalizedPower(power []float64) float64 {
+	var window [normalizedPowerWindow]float64
+
+	sum, total := 0.0, 0.0
+	n := 0
+	for _, p := range power {
+		if math.IsNaN(p) {
+			continue
+		}
+
+		sum += p - window[n%normalizedPowerWindow]
+		window[n%normalizedPowerWindow] = p
+		n++
+
+		if n >= normalizedPowerWindow {
+			total += math.Pow(sum/normalizedPowerWindow, 4)
+		}
+	}
+
+	if n < normalizedPowerWindow {
+		return 0
+	}
+	return math.Pow(total/float64(n-normalizedPowerWindow+1), 0.25)
+}
+
+// IntensityFactor returns the ratio of normalized power to functional
+// threshold power, or 0 if the threshold is not positive.
+func IntensityFactor(np, ftp float64) float64 {
+	if ftp <= 0 {
+		return 0
+	}
+	return np / ftp
+}
+
+// TSS returns the training stress score of riding for duration at the given
+// normalized power. An hour at threshold scores 100.
+func TSS(duration time.Duration, np, ftp float64) float64 {
+	if ftp <= 0 {
+		return 0
+	}
+	return duration.Hours() * IntensityFactor(np, ftp) * IntensityFactor(np, ftp) * 100
+}
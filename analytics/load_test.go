@@ -0,0 +1,90 @@
+package analytics
+
+import (
+	"math"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/kcfwpi/gofit"
+)
+
+func readActivity(t *testing.T, path string) *gofit.Activity {
+	f, ferr := os.Open(path)
+	if ferr != nil {
+		t.Fatalf("%s\n", ferr)
+	}
+	defer f.Close()
+
+	a, err := gofit.ReadActivity(f)
+	if err != nil {
+		t.Fatalf("%s: %s\n", path, err)
+	}
+	return a
+}
+
+func TestTrainingLoad(t *testing.T) {
+	for _, path := range []string{"../testfiles/test2.fit", "../testfiles/21497.fit"} {
+		a := readActivity(t, path)
+		session := a.Sessions[0]
+
+		ftp, _ := session.Float("threshold_power")
+		np, _ := session.Float("normalized_power")
+		tss, _ := session.Float("training_stress_score")
+
+		load := TrainingLoad(a, ftp)
+		// Devices differ in how they treat gaps and when they start the
+		// clock, so only expect to be close
+		if math.Abs(load.NormalizedPower-np) > 0.025*np {
+			t.Errorf("%s: got normalized power %f, device reports %f", path, load.NormalizedPower, np)
+		}
+		if math.Abs(load.TrainingStress-tss) > 0.05*tss {
+			t.Errorf("%s: got TSS %f, device reports %f", path, load.TrainingStress, tss)
+		}
+	}
+}
+
+func TestNormalizedPower(t *testing.T) {
+	steady := make([]float64, 3600)
+	for i := range steady {
+		steady[i] = 250
+	}
+	if np := NormalizedPower(steady); math.Abs(np-250) > 1e-9 {
+		t.Errorf("steady power should normalize to itself, got %f", np)
+	}
+	if tss := TSS(time.Hour, 250, 250); math.Abs(tss-100) > 1e-9 {
+		t.Errorf("an hour at threshold should score 100, got %f", tss)
+	}
+
+	// Alternating hard and easy is harder than its average
+	intervals := make([]float64, 3600)
+	for i := range intervals {
+		if i/60%2 == 0 {
+			intervals[i] = 400
+		}
+	}
+	if np := NormalizedPower(intervals); np <= 200 {
+		t.Errorf("got normalized power %f for intervals averaging 200", np)
+	}
+
+	gappy := append(append([]float64{}, steady[:60]...), math.NaN(), math.NaN())
+	if np := NormalizedPower(append(gappy, steady[:60]...)); math.Abs(np-250) > 1e-9 {
+		t.Errorf("gaps should be skipped, got %f", np)
+	}
+
+	if np := NormalizedPower(steady[:29]); np != 0 {
+		t.Errorf("too few samples should give 0, got %f", np)
+	}
+	if IntensityFactor(250, 0) != 0 {
+		t.Errorf("intensity without a threshold should be 0")
+	}
+}
+
+func TestResamplePauses(t *testing.T) {
+	a := readActivity(t, "../testfiles/test.fit")
+
+	speed := Resample(a, "speed")
+	if want := int(a.Timer(0).TimerTime() / time.Second); len(speed) < want-len(a.Timer(0).Segments) || len(speed) > want {
+		t.Errorf("got %d samples for %d seconds of timer time", len(speed), want)
+	}
+}
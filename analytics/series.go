@@ -0,0 +1,97 @@
+// Package analytics computes training metrics from decoded activities.
+//
+// Metrics are computed from series sampled once a second over the time the
+// activity's timer was running, so that pauses are left out and gaps in
+// recording are handled the same way by every metric.
+package analytics
+
+import (
+	"math"
+	"time"
+
+	"github.com/kcfwpi/gofit"
+)
+
+// MaxHold is how long a record's value is held to fill the seconds after
+// it. Devices using smart recording write records a few seconds apart;
+// longer gaps while the timer runs are treated as missing data.
+var MaxHold = 5 * time.Second
+
+// MaxPower is the highest power in watts taken to be real. Higher values
+// are sensor errors and are treated as missing.
+var MaxPower = 3000.0
+
+// Resample returns the values of the record field called name once a
+// second over the segments during which the activity's timer ran. Each
+// second takes the value of the last record at or before it, within
+// MaxHold. Seconds with no such record, or where the record has no valid
+// value, are NaN.
+func Resample(a *gofit.Activity, name string) []float64 {
+	var samples []float64
+	r := 0
+	eachSecond(a, func(t time.Time) {
+		for r < len(a.Records) && !a.Records[r].Time.After(t) {
+			r++
+		}
+
+		v := math.NaN()
+		if r > 0 {
+			last := a.Records[r-1]
+			if t.Sub(last.Time) <= MaxHold {
+				if f, ok := last.Float(name); ok {
+					v = f
+				}
+			}
+		}
+		samples = append(samples, v)
+	})
+	return samples
+}
+
+// SampleTimes returns the time of each sample Resample returns for a.
+func SampleTimes(a *gofit.Activity) []time.Time {
+	var times []time.Time
+	eachSecond(a, func(t time.Time) {
+		times = append(times, t)
+	})
+	return times
+}
+
+// eachSecond calls fn for each second the activity's timer ran, in order.
+func eachSecond(a *gofit.Activity, fn func(t time.Time)) {
+	for _, seg := range a.Timer(0).Segments {
+		n := int(seg.Duration() / time.Second)
+		for i := 0; i < n; i++ {
+			fn(seg.Start.Add(time.Duration(i) * time.Second))
+		}
+	}
+}
+
+// Power returns the activity's power resampled once a second. Values above
+// MaxPower are treated as missing, and so are NaN like any other missing
+// sample.
+func Power(a *gofit.Activity) []float64 {
+	power := Resample(a, "power")
+	for i, p := range power {
+		if p > MaxPower {
+			power[i] = math.NaN()
+		}
+	}
+	return power
+}
+
+// Speed returns the activity's speed in m/s resampled once a second.
+func Speed(a *gofit.Activity) []float64 {
+	speed := Resample(a, "enhanced_speed")
+	for i := range speed {
+		if !math.IsNaN(speed[i]) {
+			return speed
+		}
+	}
+	return Resample(a, "speed")
+}
+
+// HeartRate returns the activity's heart rate resampled once a second.
+func HeartRate(a *gofit.Activity) []float64 {
+	return Resample(a, "heart_rate")
+}
@@ -0,0 +1,131 @@
+package analytics
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/kcfwpi/gofit"
+)
+
+// WBalModel selects how W′ balance is computed.
+type WBalModel int
+
+const (
+	// Integral is Skiba's 2012 model, in which each second's expenditure
+	// above CP recovers exponentially with a time constant that depends on
+	// how far below CP the rider recovers on average.
+	Integral WBalModel = iota
+
+	// Differential is Skiba's 2015 model, in which W′ recovers below CP in
+	// proportion to the power below CP and to how depleted it is.
+	Differential
+)
+
+// WBal is the W′ balance over an activity, once a second while the timer
+// ran.
+type WBal struct {
+	Times   []time.Time
+	Balance []float64
+
+	// The lowest balance reached and when
+	Min     float64
+	MinTime time.Time
+}
+
+// WPrimeBalance computes the W′ balance of a ride given the rider's critical
+// power cp in watts and anaerobic work capacity wPrime in joules. Missing
+// power is taken as zero, and the rider recovers at zero power while the
+// timer is stopped.
+func WPrimeBalance(a *gofit.Activity, cp, wPrime float64, model WBalModel) WBal {
+	return ComputeWBal(Power(a), SampleTimes(a), cp, wPrime, model)
+}
+
+// ComputeWBal computes the W′ balance of power samples taken once a second
+// at times. See WPrimeBalance.
+func ComputeWBal(power []float64, times []time.Time, cp, wPrime float64, model WBalModel) WBal {
+	w := WBal{Times: times, Balance: make([]float64, len(power)), Min: wPrime}
+
+	// Seconds of recovery at zero power before each sample, from pauses
+	rest := func(i int) float64 {
+		if i == 0 {
+			return 0
+		}
+		return math.Max(0, times[i].Sub(times[i-1]).Seconds()-1)
+	}
+
+	watts := func(i int) float64 {
+		if math.IsNaN(power[i]) {
+			return 0
+		}
+		return power[i]
+	}
+
+	switch model {
+	case Integral:
+		tau := wbalTau(power, cp)
+
+		// Expenditure so far, each second's decayed by the time since
+		expended := 0.0
+		for i := range power {
+			expended *= math.Exp(-(rest(i) + 1) / tau)
+			expended += math.Max(0, watts(i)-cp)
+			w.Balance[i] = wPrime - expended
+		}
+	case Differential:
+		balance := wPrime
+		recover := func(p, seconds float64) {
+			balance = wPrime - (wPrime-balance)*math.Exp(-(cp-p)*seconds/wPrime)
+		}
+
+		for i := range power {
+			recover(0, rest(i))
+			if p := watts(i); p < cp {
+				recover(p, 1)
+			} else {
+				balance -= p - cp
+			}
+			w.Balance[i] = balance
+		}
+	}
+
+	for i, b := range w.Balance {
+		if b < w.Min {
+			w.Min, w.MinTime = b, times[i]
+		}
+	}
+	return w
+}
+
+// wbalTau returns the recovery time constant of the integral model, which
+// depends on the mean power of the samples below cp.
+func wbalTau(power []float64, cp float64) float64 {
+	sum, n := 0.0, 0
+	for _, p := range power {
+		if math.IsNaN(p) {
+			p = 0
+		}
+		if p < cp {
+			sum += p
+			n++
+		}
+	}
+
+	below := 0.0
+	if n > 0 {
+		below = cp - sum/float64(n)
+	}
+	return 546*math.Exp(-0.01*below) + 316
+}
+
+// At returns the balance at time t, that of the last sample at or before
+// it. It returns false if t is before the first sample.
+func (w WBal) At(t time.Time) (float64, bool) {
+	i := sort.Search(len(w.Times), func(i int) bool {
+		return w.Times[i].After(t)
+	})
+	if i == 0 {
+		return 0, false
+	}
+	return w.Balance[i-1], true
+}
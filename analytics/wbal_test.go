@@ -0,0 +1,92 @@
+package analytics
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func wbalSeries(power ...[2]float64) ([]float64, []time.Time) {
+	start := time.Unix(1431558100, 0)
+
+	var samples []float64
+	var times []time.Time
+	for _, p := range power {
+		for i := 0; i < int(p[1]); i++ {
+			times = append(times, start.Add(time.Duration(len(samples))*time.Second))
+			samples = append(samples, p[0])
+		}
+	}
+	return samples, times
+}
+
+func TestWBalDepletion(t *testing.T) {
+	// A minute 100W over CP then ten minutes of easy riding
+	power, times := wbalSeries([2]float64{350, 60}, [2]float64{100, 600})
+
+	diff := ComputeWBal(power, times, 250, 20000, Differential)
+	integral := ComputeWBal(power, times, 250, 20000, Integral)
+
+	if b := diff.Balance[59]; math.Abs(b-14000) > 1e-9 {
+		t.Errorf("got differential balance %f after a minute", b)
+	}
+	if diff.Min != diff.Balance[59] || !diff.MinTime.Equal(times[59]) {
+		t.Errorf("got minimum %f at %s", diff.Min, diff.MinTime)
+	}
+
+	// The integral model recovers a little even while working
+	if b := integral.Balance[59]; b < 14000 || b > 14500 {
+		t.Errorf("got integral balance %f after a minute", b)
+	}
+
+	for _, w := range []WBal{diff, integral} {
+		for i := 61; i < len(w.Balance); i++ {
+			if w.Balance[i] < w.Balance[i-1] || w.Balance[i] > 20000 {
+				t.Fatalf("balance should recover towards W′, got %f after %f", w.Balance[i], w.Balance[i-1])
+			}
+		}
+		if w.Balance[len(w.Balance)-1] < 18000 {
+			t.Errorf("got balance %f after ten minutes recovery", w.Balance[len(w.Balance)-1])
+		}
+	}
+}
+
+func TestWBalPause(t *testing.T) {
+	power, times := wbalSeries([2]float64{400, 60}, [2]float64{400, 60})
+
+	// Stop the timer for five minutes between the efforts
+	paused := append([]time.Time{}, times...)
+	for i := 60; i < len(paused); i++ {
+		paused[i] = paused[i].Add(5 * time.Minute)
+	}
+
+	for _, model := range []WBalModel{Integral, Differential} {
+		straight := ComputeWBal(power, times, 250, 20000, model)
+		rested := ComputeWBal(power, paused, 250, 20000, model)
+		if rested.Min <= straight.Min {
+			t.Errorf("model %d: pause should allow recovery, got %f and %f", model, rested.Min, straight.Min)
+		}
+	}
+}
+
+func TestWPrimeBalance(t *testing.T) {
+	a := readActivity(t, "../testfiles/test2.fit")
+
+	w := WPrimeBalance(a, 260, 20000, Differential)
+	if len(w.Balance) != len(w.Times) || len(w.Balance) == 0 {
+		t.Fatalf("got %d balances for %d times", len(w.Balance), len(w.Times))
+	}
+	if w.Min >= 20000 || w.MinTime.IsZero() {
+		t.Errorf("a ride averaging over CP should deplete W′, got minimum %f", w.Min)
+	}
+
+	for _, r := range a.Records {
+		if b, ok := w.At(r.Time); !ok || b > 20000 {
+			t.Errorf("got balance %f at %s", b, r.Time)
+			break
+		}
+	}
+	if _, ok := w.At(w.Times[0].Add(-time.Second)); ok {
+		t.Errorf("no balance before the ride")
+	}
+}
@@ -0,0 +1,204 @@
+package analytics
+
+import (
+	"math"
+	"time"
+
+	"github.com/kcfwpi/gofit"
+)
+
+// Zones holds the upper bounds of a set of training zones in increasing
+// order. A value is in the first zone whose bound is above it, and values
+// at or above the last bound are in an extra, final zone, so there are
+// len(zones)+1 zones. This is how devices count time_in_zone.
+type Zones []float64
+
+// Zone returns the zone v is in.
+func (z Zones) Zone(v float64) int {
+	for i, bound := range z {
+		if v < bound {
+			return i
+		}
+	}
+	return len(z)
+}
+
+// Percentages of maximum heart rate and of functional threshold power that
+// bound the default zones
+var (
+	heartRateZonePercentages = []float64{50, 60, 70, 80, 90, 100}
+	powerZonePercentages     = []float64{55, 75, 90, 105, 120, 150}
+)
+
+// HeartRateZonesFromMax returns the default heart rate zones for a maximum
+// heart rate: 50, 60, 70, 80 and 90% of it and the maximum itself.
+func HeartRateZonesFromMax(max float64) Zones {
+	return percentages(max, heartRateZonePercentages)
+}
+
+// PowerZonesFromFTP returns the classic seven power zones for a functional
+// threshold power: recovery below 55% of it, then endurance, tempo,
+// threshold, VO2 max and anaerobic up to 150% and neuromuscular above.
+func PowerZonesFromFTP(ftp float64) Zones {
+	return percentages(ftp, powerZonePercentages)
+}
+
+func percentages(of float64, percentages []float64) Zones {
+	zones := make(Zones, len(percentages))
+	for i, p := range percentages {
+		zones[i] = math.Round(of * p / 100)
+	}
+	return zones
+}
+
+// HeartRateZones returns the heart rate zones stored in an activity. These
+// are taken from hr_zone messages, from the boundaries of a time_in_zone
+// message, or derived from the maximum heart rate in the zones_target or
+// user_profile message, in that order of preference.
+func HeartRateZones(a *gofit.Activity) (Zones, bool) {
+	if zones := zoneMessages(a, gofit.MesgHrZone, "high_bpm"); zones != nil {
+		return zones, true
+	}
+	if zones := timeInZoneBoundaries(a, "hr_zone_high_boundary"); zones != nil {
+		return zones, true
+	}
+	if max, ok := setting(a, gofit.MesgZonesTarget, "max_heart_rate"); ok {
+		return HeartRateZonesFromMax(max), true
+	}
+	if max, ok := setting(a, gofit.MesgUserProfile, "default_max_heart_rate"); ok {
+		return HeartRateZonesFromMax(max), true
+	}
+	return nil, false
+}
+
+// PowerZones returns the power zones stored in an activity, from
+// power_zone messages, the boundaries of a time_in_zone message, or derived
+// from the functional threshold power in the zones_target message.
+func PowerZones(a *gofit.Activity) (Zones, bool) {
+	if zones := zoneMessages(a, gofit.MesgPowerZone, "high_value"); zones != nil {
+		return zones, true
+	}
+	if zones := timeInZoneBoundaries(a, "power_zone_high_boundary"); zones != nil {
+		return zones, true
+	}
+	if ftp, ok := setting(a, gofit.MesgZonesTarget, "functional_threshold_power"); ok {
+		return PowerZonesFromFTP(ftp), true
+	}
+	return nil, false
+}
+
+// SpeedZones returns the speed zones, in m/s, stored in an activity, from
+// speed_zone messages or the boundaries of a time_in_zone message. Pace
+// zones are speed zones in reverse.
+func SpeedZones(a *gofit.Activity) (Zones, bool) {
+	if zones := zoneMessages(a, gofit.MesgSpeedZone, "high_value"); zones != nil {
+		return zones, true
+	}
+	if zones := timeInZoneBoundaries(a, "speed_zone_high_boundary"); zones != nil {
+		return zones, true
+	}
+	return nil, false
+}
+
+// zoneMessages collects the high values of the zone messages of type
+// mesgNum, which list one zone each in order.
+func zoneMessages(a *gofit.Activity, mesgNum uint16, name string) Zones {
+	var zones Zones
+	for _, m := range a.Messages {
+		if m.Type != mesgNum {
+			continue
+		}
+		if v, ok := m.Float(name); ok {
+			zones = append(zones, v)
+		}
+	}
+	return zones
+}
+
+// timeInZoneBoundaries returns the zone boundaries recorded in the
+// activity's time_in_zone messages, preferring the session's.
+func timeInZoneBoundaries(a *gofit.Activity, name string) Zones {
+	var zones Zones
+	for _, m := range a.Messages {
+		if m.Type != gofit.MesgTimeInZone {
+			continue
+		}
+
+		bounds := validFloats(m.Floats(name))
+		if len(bounds) == 0 {
+			continue
+		}
+		if ref, _ := m.Float("reference_mesg"); ref == float64(gofit.MesgSession) {
+			return bounds
+		}
+		if zones == nil {
+			zones = bounds
+		}
+	}
+	return zones
+}
+
+// setting returns a positive value of the field called name of the first
+// message of type mesgNum that has one.
+func setting(a *gofit.Activity, mesgNum uint16, name string) (float64, bool) {
+	for _, m := range a.Messages {
+		if m.Type != mesgNum {
+			continue
+		}
+		if v, ok := m.Float(name); ok && v > 0 {
+			return v, true
+		}
+	}
+	return 0, false
+}
+
+func validFloats(values []float64) Zones {
+	var valid Zones
+	for _, v := range values {
+		if !math.IsNaN(v) {
+			valid = append(valid, v)
+		}
+	}
+	return valid
+}
+
+// TimeInZones returns the time spent in each zone by samples taken once a
+// second. Missing samples are not counted.
+func TimeInZones(samples []float64, zones Zones) []time.Duration {
+	times := make([]time.Duration, len(zones)+1)
+	for _, v := range samples {
+		if !math.IsNaN(v) {
+			times[zones.Zone(v)] += time.Second
+		}
+	}
+	return times
+}
+
+// ActivityTimeInZones returns the time spent in each zone over an activity
+// by the series metric returns, such as Power, HeartRate or Speed.
+func ActivityTimeInZones(a *gofit.Activity, metric func(*gofit.Activity) []float64, zones Zones) []time.Duration {
+	return TimeInZones(metric(a), zones)
+}
+
+// LapTimeInZones returns the time spent in each zone during each lap of an
+// activity, in lap order. A lap runs from its start_time up to its
+// timestamp.
+func LapTimeInZones(a *gofit.Activity, metric func(*gofit.Activity) []float64, zones Zones) [][]time.Duration {
+	samples := metric(a)
+	times := SampleTimes(a)
+
+	laps := make([][]time.Duration, len(a.Laps))
+	for i, lap := range a.Laps {
+		start, _ := lap.Timestamp("start_time")
+		end := lap.Time
+
+		var lapSamples []float64
+		for j, t := range times {
+			if !t.Before(start) && t.Before(end) {
+				lapSamples = append(lapSamples, samples[j])
+			}
+		}
+		laps[i] = TimeInZones(lapSamples, zones)
+	}
+	return laps
+}
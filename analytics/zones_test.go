@@ -0,0 +1,80 @@
+package analytics
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kcfwpi/gofit"
+)
+
+func TestZone(t *testing.T) {
+	zones := Zones{100, 120, 140}
+	for v, want := range map[float64]int{50: 0, 100: 1, 119: 1, 139.5: 2, 140: 3, 200: 3} {
+		if got := zones.Zone(v); got != want {
+			t.Errorf("%f: got zone %d, want %d", v, got, want)
+		}
+	}
+
+	times := TimeInZones([]float64{50, 100, math.NaN(), 150, 160}, zones)
+	if !reflect.DeepEqual(times, []time.Duration{time.Second, time.Second, 0, 2 * time.Second}) {
+		t.Errorf("got times %v", times)
+	}
+}
+
+func TestZonesFromFile(t *testing.T) {
+	a := readActivity(t, "../testfiles/test.fit")
+
+	// From zones_target
+	if zones, ok := HeartRateZones(a); !ok || !reflect.DeepEqual(zones, HeartRateZonesFromMax(178)) {
+		t.Errorf("got heart rate zones %v", zones)
+	}
+	if zones, ok := PowerZones(a); !ok || !reflect.DeepEqual(zones, Zones{110, 150, 180, 210, 240, 300}) {
+		t.Errorf("got power zones %v", zones)
+	}
+	if _, ok := SpeedZones(a); ok {
+		t.Errorf("test.fit has no speed zones")
+	}
+
+	// From time_in_zone
+	a = readActivity(t, "../testfiles/fit2-2.fit")
+	if zones, ok := PowerZones(a); !ok || !reflect.DeepEqual(zones, Zones{79, 173, 236, 284, 331, 378, 473, 630}) {
+		t.Errorf("got power zones %v", zones)
+	}
+}
+
+func TestTimeInZonesMatchesDevice(t *testing.T) {
+	a := readActivity(t, "../testfiles/devdata.fit")
+
+	zones, ok := HeartRateZones(a)
+	if !ok || !reflect.DeepEqual(zones, Zones{94, 151, 162, 166, 170, 188}) {
+		t.Fatalf("got heart rate zones %v", zones)
+	}
+
+	activity := ActivityTimeInZones(a, HeartRate, zones)
+	laps := LapTimeInZones(a, HeartRate, zones)
+	if len(laps) != len(a.Laps) {
+		t.Fatalf("got %d laps", len(laps))
+	}
+
+	for _, m := range a.Messages {
+		if m.Type != gofit.MesgTimeInZone {
+			continue
+		}
+
+		ref, _ := m.Float("reference_mesg")
+		index, _ := m.Float("reference_index")
+
+		got := activity
+		if ref == float64(gofit.MesgLap) {
+			got = laps[int(index)]
+		}
+
+		for i, want := range m.Floats("time_in_hr_zone") {
+			if d := got[i].Seconds() - want; d < -3 || d > 3 {
+				t.Errorf("message %v %v zone %d: got %s, device reports %.3fs", ref, index, i, got[i], want)
+			}
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package gofit
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+)
+
+// Fields identifying a device, a user or an application, by message
+var identifierFields = map[uint16][]byte{
+	MesgFileID:          {3},      // serial_number
+	MesgDeviceInfo:      {3, 21},  // serial_number, ant_device_number
+	MesgUserProfile:     {22, 23}, // local_id, global_id
+	MesgDeveloperDataID: {0, 1},   // developer_id, application_id
+}
+
+// Fields describing the user, by message
+var personalFields = map[uint16][]byte{
+	// friendly_name, gender, age, height, weight, wake_time, sleep_time
+	MesgUserProfile: {0, 1, 2, 3, 4, 28, 29},
+}
+
+// Anonymizer removes identifying data from messages so files can be shared
+// in bug reports or test corpora. Personal data in the user profile, such
+// as the user's name, age and weight, is always scrubbed. Identifiers such
+// as the serial numbers in file_id and device_info, ANT+ device numbers and
+// the developer and application ids of developer data are hashed with Key
+// if it is set, and scrubbed otherwise. Hashing keeps identifiers distinct
+// and consistent across files anonymized with the same key without
+// revealing them. Scrubbed fields are set to the invalid value of their
+// type.
+type Anonymizer struct {
+	Key []byte
+}
+
+// Apply anonymizes m in place. Only fields read from the file are changed.
+func (an Anonymizer) Apply(m *Message) {
+	order := byteOrder(m.Arch)
+
+	for _, num := range personalFields[m.Type] {
+		if f := m.FieldNum(num); f != nil {
+			clearRaw(f, order)
+		}
+	}
+
+	for _, num := range identifierFields[m.Type] {
+		f := m.FieldNum(num)
+		if f == nil || f.Raw == nil || f.Value == nil {
+			continue
+		}
+
+		if an.Key == nil {
+			clearRaw(f, order)
+			continue
+		}
+
+		an.hash(f, order)
+		f.Value = decodeValue(f.Raw, f.Type, order, LookupProfile(m.Type).Field(num))
+	}
+}
+
+// hash replaces the raw bytes of f with a keyed hash of its value. The
+// value is hashed, and the hash read as elements, in little endian order,
+// so the same serial number hashes the same in files of either
+// architecture. Elements that hash to the invalid value of their type are
+// nudged off it.
+func (an Anonymizer) hash(f *Field, order binary.ByteOrder) {
+	value := littleEndianBits(f.Raw, f.Type, order)
+
+	var sum []byte
+	for counter := byte(0); len(sum) < len(f.Raw); counter++ {
+		mac := hmac.New(sha256.New, an.Key)
+		mac.Write([]byte{f.Type, counter})
+		mac.Write(value)
+		sum = mac.Sum(sum)
+	}
+	copy(f.Raw, littleEndianBits(sum[:len(f.Raw)], f.Type, order))
+
+	if f.Type == TypeByte || f.Type == TypeString || int(f.Type) >= len(baseTypeSizes) {
+		// Byte arrays are only invalid if every byte is 0xFF
+		if f.Type == TypeByte && f.Raw[0] == 0xFF {
+			f.Raw[0] = 0
+		}
+		return
+	}
+
+	size := baseTypeSize(f.Type)
+	for i := 0; i+size <= len(f.Raw); i += size {
+		if _, ok := elementValue(f.Raw[i:i+size], f.Type, order); !ok {
+			f.Raw[i] ^= 1
+		}
+	}
+}
+
+// Anonymize decodes the FIT file read from r, anonymizes every message with
+// an and writes it to w.
+func Anonymize(w io.Writer, r io.Reader, an Anonymizer) error {
+	d := NewDecoder(r)
+	e := NewEncoder(w)
+	for {
+		m, err := d.Next()
+		if err == io.EOF {
+			return e.Close()
+		}
+		if err != nil {
+			return err
+		}
+
+		an.Apply(m)
+		if err := e.Encode(m); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package gofit
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func anonymize(t *testing.T, path string, an Anonymizer) (*Activity, *Activity) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%s\n", err)
+	}
+
+	var buf bytes.Buffer
+	if err := Anonymize(&buf, bytes.NewReader(data), an); err != nil {
+		t.Fatalf("%s: %s", path, err)
+	}
+	if crc := CRC(buf.Bytes()); crc != 0 {
+		t.Errorf("%s: got file CRC %04x", path, crc)
+	}
+
+	a, _ := ReadActivity(bytes.NewReader(data))
+	b, err := ReadActivity(&buf)
+	if err != nil {
+		t.Fatalf("%s: %s", path, err)
+	}
+	if len(b.Messages) != len(a.Messages) {
+		t.Fatalf("%s: got %d messages, want %d", path, len(b.Messages), len(a.Messages))
+	}
+	return a, b
+}
+
+func TestAnonymizeScrub(t *testing.T) {
+	a, b := anonymize(t, "testfiles/test.fit", Anonymizer{})
+
+	if _, ok := b.FileID.Float("serial_number"); ok {
+		t.Errorf("file_id serial number kept")
+	}
+	for _, m := range b.DeviceInfos {
+		if _, ok := m.Float("serial_number"); ok {
+			t.Fatalf("device_info serial number kept")
+		}
+	}
+
+	for i, m := range b.Messages {
+		switch m.Type {
+		case MesgUserProfile:
+			for _, name := range []string{"friendly_name", "age", "weight"} {
+				if f := m.Field(name); f == nil || f.Value != nil {
+					t.Errorf("user_profile %s kept", name)
+				}
+			}
+			if v, ok := m.Float("default_max_heart_rate"); !ok || v == 0 {
+				t.Errorf("user_profile settings changed")
+			}
+		case MesgRecord:
+			want, _ := a.Messages[i].Float("heart_rate")
+			if got, _ := m.Float("heart_rate"); got != want {
+				t.Fatalf("record %d changed", i)
+			}
+		}
+	}
+}
+
+func TestAnonymizeHash(t *testing.T) {
+	an := Anonymizer{Key: []byte("corpus")}
+	a, b := anonymize(t, "testfiles/fit2-2.fit", an)
+	_, c := anonymize(t, "testfiles/fit2-2.fit", an)
+
+	serial, _ := a.FileID.Float("serial_number")
+	hashed, ok := b.FileID.Float("serial_number")
+	if !ok || hashed == serial {
+		t.Errorf("got serial number %f for %f", hashed, serial)
+	}
+	if again, _ := c.FileID.Float("serial_number"); again != hashed {
+		t.Errorf("hashes differ between runs")
+	}
+
+	// The same serial number hashes the same in a big endian file
+	data, err := os.ReadFile("testfiles/fit2-2.fit")
+	if err != nil {
+		t.Fatalf("%s\n", err)
+	}
+	var buf bytes.Buffer
+	if err := Anonymize(&buf, bytes.NewReader(toBigEndian(t, data)), an); err != nil {
+		t.Fatalf("%s\n", err)
+	}
+	big, err := ReadActivity(&buf)
+	if err != nil {
+		t.Fatalf("%s\n", err)
+	}
+	if big.FileID.Arch != 1 {
+		t.Fatalf("file_id is not big endian")
+	}
+	if v, _ := big.FileID.Float("serial_number"); v != hashed {
+		t.Errorf("got big endian serial number %f, want %f", v, hashed)
+	}
+
+	// The creator's device_info shares the file_id's serial number and
+	// should still do so
+	for _, m := range b.DeviceInfos {
+		if index, _ := m.Float("device_index"); index == 0 {
+			if v, _ := m.Float("serial_number"); v != hashed {
+				t.Errorf("got creator serial number %f, want %f", v, hashed)
+			}
+		}
+	}
+
+	for i, m := range b.Messages {
+		if m.Type != MesgDeveloperDataID {
+			continue
+		}
+		if got, want := m.Bytes("application_id"), a.Messages[i].Bytes("application_id"); got == nil || bytes.Equal(got, want) {
+			t.Errorf("got application id %v", got)
+		}
+	}
+
+	// Developer fields are left alone
+	for i, m := range b.Records {
+		if len(m.DevFields) != len(a.Records[i].DevFields) {
+			t.Fatalf("record %d: developer fields changed", i)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package gofit
+
+// Bits of left_right_balance and left_right_balance_100 values
+const (
+	balanceRight    = 0x80
+	balanceMask     = 0x7F
+	balance100Right = 0x8000
+	balance100Mask  = 0x3FFF
+)
+
+// LeftRightBalance is the share of power contributed by one leg, as power
+// meters report it.
+type LeftRightBalance struct {
+	// Percentage of the total contributed by one side
+	Percent float64
+
+	// Whether Percent is the right leg's contribution. Otherwise the meter
+	// did not know which leg it measured.
+	Right bool
+}
+
+// Left returns the left leg's share, if the side of the balance is known.
+func (b LeftRightBalance) Left() (float64, bool) {
+	if !b.Right {
+		return 0, false
+	}
+	return 100 - b.Percent, true
+}
+
+// Balance decodes the left/right balance field called name. Record messages
+// hold a left_right_balance, a whole percentage in the low 7 bits with the
+// top bit set if it is the right leg's. Laps and sessions hold a
+// left_right_balance_100, hundredths of a percent in the low 14 bits with
+// the top bit as the right flag. The field's Value is the undecoded number.
+func (m *Message) Balance(name string) (LeftRightBalance, bool) {
+	f := m.Field(name)
+	if f == nil {
+		return LeftRightBalance{}, false
+	}
+	v, ok := f.Value.(float64)
+	if !ok {
+		return LeftRightBalance{}, false
+	}
+
+	bits := uint16(v)
+	if f.Type == TypeUint8 {
+		return LeftRightBalance{Percent: float64(bits & balanceMask), Right: bits&balanceRight != 0}, true
+	}
+	return LeftRightBalance{Percent: float64(bits&balance100Mask) / 100, Right: bits&balance100Right != 0}, true
+}
@@ -0,0 +1,37 @@
+package gofit
+
+import (
+	"testing"
+)
+
+func TestBalance(t *testing.T) {
+	data := testFile(
+		testDefinition(0, 0, MesgRecord, [3]byte{30, 1, TypeUint8}),
+		testData(0, []byte{0x80 | 52}),
+		testData(0, []byte{48}),
+		testData(0, []byte{0xFF}),
+		testDefinition(1, 0, MesgLap, [3]byte{34, 2, TypeUint16}),
+		testData(1, le16(0x8000|5125)),
+	)
+
+	messages := decodeAll(t, data)
+
+	b, ok := messages[0].Balance("left_right_balance")
+	if left, lok := b.Left(); !ok || !b.Right || b.Percent != 52 || !lok || left != 48 {
+		t.Errorf("got balance %+v", b)
+	}
+
+	b, ok = messages[1].Balance("left_right_balance")
+	if _, lok := b.Left(); !ok || b.Right || b.Percent != 48 || lok {
+		t.Errorf("a balance without the right flag has no known side, got %+v", b)
+	}
+
+	if _, ok := messages[2].Balance("left_right_balance"); ok {
+		t.Errorf("invalid balance should not decode")
+	}
+
+	b, ok = messages[3].Balance("left_right_balance")
+	if left, _ := b.Left(); !ok || !b.Right || b.Percent != 51.25 || left != 48.75 {
+		t.Errorf("got lap balance %+v", b)
+	}
+}
@@ -0,0 +1,181 @@
+package gofit
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BatchResult is the outcome of decoding one file of a batch.
+type BatchResult struct {
+	Path     string
+	Messages []DataMessage
+	Err      error
+}
+
+// BatchStats summarises a decoded batch.
+type BatchStats struct {
+	Files    int
+	Failed   int
+	Messages int
+	Elapsed  time.Duration
+
+	// Number of failed files for each kind of error
+	Failures map[string]int
+}
+
+// FilesPerSecond returns the rate files were decoded at.
+func (s BatchStats) FilesPerSecond() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+	return float64(s.Files) / s.Elapsed.Seconds()
+}
+
+type batchJob struct {
+	path   string
+	result chan BatchResult
+}
+
+// DecodeFS decodes the files at paths within fsys using at most workers
+// goroutines, or runtime.NumCPU() if workers is not positive. If paths is
+// nil every file with a .fit extension in fsys is decoded, in lexical order.
+//
+// handle is called from the calling goroutine with each file's result in the
+// order of paths, regardless of the order decoding finishes in.
+func DecodeFS(fsys fs.FS, paths []string, workers int, handle func(BatchResult)) (BatchStats, error) {
+	if paths == nil {
+		err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && strings.EqualFold(path.Ext(p), ".fit") {
+				paths = append(paths, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return BatchStats{}, err
+		}
+	}
+
+	open := func(p string) (io.ReadCloser, error) {
+		return fsys.Open(p)
+	}
+
+	return decodeBatch(open, paths, workers, handle), nil
+}
+
+// DecodeFiles decodes the files at paths on disk as DecodeFS does.
+func DecodeFiles(paths []string, workers int, handle func(BatchResult)) BatchStats {
+	open := func(p string) (io.ReadCloser, error) {
+		return os.Open(p)
+	}
+
+	return decodeBatch(open, paths, workers, handle)
+}
+
+func decodeBatch(open func(string) (io.ReadCloser, error), paths []string, workers int, handle func(BatchResult)) BatchStats {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	stats := BatchStats{Failures: make(map[string]int)}
+	start := time.Now()
+
+	// Results are queued in path order. The queue's capacity bounds how far
+	// decoding can run ahead of a slow file.
+	jobs := make(chan batchJob)
+	pending := make(chan chan BatchResult, workers)
+
+	go func() {
+		for _, p := range paths {
+			result := make(chan BatchResult, 1)
+			pending <- result
+			jobs <- batchJob{path: p, result: result}
+		}
+		close(jobs)
+		close(pending)
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			// Each worker reuses its buffers for every file it decodes.
+			// Reset forgets the definitions of the previous file, so each
+			// result depends only on the file itself.
+			br := bufio.NewReader(nil)
+			r := NewReader(br)
+
+			for job := range jobs {
+				job.result <- decodeBatchFile(open, job.path, br, r)
+			}
+		}()
+	}
+
+	for result := range pending {
+		res := <-result
+
+		stats.Files++
+		stats.Messages += len(res.Messages)
+		if res.Err != nil {
+			stats.Failed++
+			stats.Failures[errorKind(res.Err)]++
+		}
+
+		if handle != nil {
+			handle(res)
+		}
+	}
+
+	wg.Wait()
+	stats.Elapsed = time.Since(start)
+
+	return stats
+}
+
+func decodeBatchFile(open func(string) (io.ReadCloser, error), p string, br *bufio.Reader, r *Reader) BatchResult {
+	res := BatchResult{Path: p}
+
+	f, err := open(p)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	defer f.Close()
+
+	br.Reset(f)
+	r.Reset(br)
+
+	for {
+		m, err := r.Next()
+		if err == io.EOF {
+			return res
+		}
+		if err != nil {
+			res.Err = err
+			return res
+		}
+		res.Messages = append(res.Messages, m.DataMessage())
+	}
+}
+
+// errorKind groups errors for BatchStats. Errors from this package are
+// distinguished by their message, errors opening a file by their cause.
+func errorKind(err error) string {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return "open: " + pathErr.Err.Error()
+	}
+	return err.Error()
+}
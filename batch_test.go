@@ -0,0 +1,124 @@
+package gofit
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"testing/fstest"
+)
+
+func TestDecodeFS(t *testing.T) {
+	files := readTestFiles(t)
+
+	var order []string
+	stats, err := DecodeFS(os.DirFS("testfiles"), nil, 3, func(res BatchResult) {
+		order = append(order, res.Path)
+
+		r := NewReader(bytes.NewReader(files[filepath.Join("testfiles", res.Path)]))
+		n := 0
+		for {
+			if _, nerr := r.Next(); nerr != nil {
+				break
+			}
+			n++
+		}
+
+		if res.Path == "bad.fit" {
+			if res.Err == nil {
+				t.Errorf("bad.fit: expected an error")
+			}
+			return
+		}
+		if res.Err != nil {
+			t.Errorf("%s: %s", res.Path, res.Err)
+		}
+		if len(res.Messages) != n {
+			t.Errorf("%s: got %d messages, want %d", res.Path, len(res.Messages), n)
+		}
+	})
+	if err != nil {
+		t.Fatalf("%s\n", err)
+	}
+
+	if len(order) != len(files) || !sort.StringsAreSorted(order) {
+		t.Errorf("results out of order: %v", order)
+	}
+
+	if stats.Files != len(files) || stats.Failed != 1 {
+		t.Errorf("got %d files with %d failures, want %d with 1", stats.Files, stats.Failed, len(files))
+	}
+	if len(stats.Failures) != 1 {
+		t.Errorf("got failures %v", stats.Failures)
+	}
+	if stats.FilesPerSecond() <= 0 {
+		t.Errorf("got %f files/s", stats.FilesPerSecond())
+	}
+}
+
+func TestDecodeFiles(t *testing.T) {
+	paths := []string{"testfiles/test2.fit", "testfiles/missing.fit", "testfiles/test.fit", "testfiles/missing2.fit"}
+
+	i := 0
+	stats := DecodeFiles(paths, 2, func(res BatchResult) {
+		if res.Path != paths[i] {
+			t.Errorf("result %d: got %s, want %s", i, res.Path, paths[i])
+		}
+		if (res.Err != nil) != (i%2 == 1) {
+			t.Errorf("%s: unexpected error state %v", res.Path, res.Err)
+		}
+		i++
+	})
+
+	if stats.Files != 4 || stats.Failed != 2 {
+		t.Errorf("got %d files with %d failures", stats.Files, stats.Failed)
+	}
+	if stats.Failures["open: no such file or directory"] != 2 {
+		t.Errorf("got failures %v", stats.Failures)
+	}
+}
+
+func TestDecodeFSDeterministic(t *testing.T) {
+	data, err := os.ReadFile("testfiles/test2.fit")
+	if err != nil {
+		t.Fatalf("%s\n", err)
+	}
+
+	// Files without definitions between valid ones, so a worker that kept
+	// the definitions of its previous file would decode them
+	undefined := testFile(testData(0, make([]byte, 40)))
+	fsys := fstest.MapFS{}
+	corrupt := make(map[string]bool)
+	for i := 0; i < 12; i++ {
+		name := fmt.Sprintf("%02d.fit", i)
+		if i%3 == 1 {
+			fsys[name] = &fstest.MapFile{Data: undefined}
+			corrupt[name] = true
+		} else {
+			fsys[name] = &fstest.MapFile{Data: data}
+		}
+	}
+
+	var want []string
+	for _, workers := range []int{1, 2, 3, 8} {
+		var got []string
+		_, err := DecodeFS(fsys, nil, workers, func(res BatchResult) {
+			got = append(got, fmt.Sprintf("%s %d %v", res.Path, len(res.Messages), res.Err))
+
+			if corrupt[res.Path] != (res.Err != nil) {
+				t.Errorf("%d workers: %s: got error %v", workers, res.Path, res.Err)
+			}
+		})
+		if err != nil {
+			t.Fatalf("%s\n", err)
+		}
+
+		if want == nil {
+			want = got
+		} else if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("%d workers: got %v, want %v", workers, got, want)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package gofit
+
+// Nibble lookup table of the FIT CRC-16
+var crcTable = [16]uint16{
+	0x0000, 0xCC01, 0xD801, 0x1400, 0xF001, 0x3C00, 0x2800, 0xE401,
+	0xA001, 0x6C00, 0x7800, 0xB401, 0x5000, 0x9C01, 0x8801, 0x4400,
+}
+
+// CRC returns the FIT CRC-16 of data. A FIT file ends with the CRC of the
+// bytes before it, so the CRC of a whole valid file is 0. The same CRC
+// protects 14 byte file headers.
+func CRC(data []byte) uint16 {
+	return updateCRC(0, data)
+}
+
+// updateCRC continues the CRC crc over data.
+func updateCRC(crc uint16, data []byte) uint16 {
+	for _, b := range data {
+		tmp := crcTable[crc&0xF]
+		crc = (crc >> 4) & 0x0FFF
+		crc = crc ^ tmp ^ crcTable[b&0xF]
+
+		tmp = crcTable[crc&0xF]
+		crc = (crc >> 4) & 0x0FFF
+		crc = crc ^ tmp ^ crcTable[(b>>4)&0xF]
+	}
+	return crc
+}
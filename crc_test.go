@@ -0,0 +1,25 @@
+package gofit
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestCRC(t *testing.T) {
+	for path, data := range readTestFiles(t) {
+		if path == "testfiles/bad.fit" {
+			continue
+		}
+
+		if crc := CRC(data); crc != 0 {
+			t.Errorf("%s: got CRC %04x over the whole file", path, crc)
+		}
+
+		// The header CRC covers the first 12 bytes
+		if data[0] == 14 {
+			if crc := binary.LittleEndian.Uint16(data[12:14]); crc != 0 && crc != CRC(data[:12]) {
+				t.Errorf("%s: header CRC %04x, computed %04x", path, crc, CRC(data[:12]))
+			}
+		}
+	}
+}
@@ -0,0 +1,598 @@
+package gofit
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/kcfwpi/gofit/units"
+)
+
+// Message is a data message decoded against the FIT profile.
+type Message struct {
+	Type uint16
+	Name string
+	Arch byte
+
+	// Time of the message, reconstructed from the last full timestamp if the
+	// message only carries part of one. Zero if the time is not known.
+	Time time.Time
+
+	// Fields in the order they were defined, followed by any fields
+	// expanded from components
+	Fields    []Field
+	DevFields []DevField
+}
+
+// Field is a decoded field. Value holds a float64 for numeric fields, a
+// []float64 for numeric arrays, a string for strings, a []string for string
+// arrays and a []byte for byte arrays, or nil if the field holds the invalid
+// value for its type. Invalid elements of numeric arrays are NaN.
+type Field struct {
+	Num   byte
+	Name  string
+	Units string
+	Type  byte
+	Value interface{}
+
+	// Bytes as read from the file, nil for fields expanded from components
+	Raw []byte
+}
+
+// DevField is a developer data field.
+type DevField struct {
+	DevDataIdx byte
+	Num        byte
+	Raw        []byte
+}
+
+// Decoder decodes data messages against the FIT profile, applying scale and
+// offset, expanding components into the fields they describe and keeping
+// the running totals of accumulated fields across the stream.
+type Decoder struct {
+	r *Reader
+
+	accumulators map[uint32]*accumulator
+	times        timestamps
+
+	units units.System
+}
+
+// DecoderOption configures a Decoder.
+type DecoderOption func(*Decoder)
+
+// WithUnits makes a Decoder present values in the unit system sys rather
+// than the units of the FIT profile. Field units are updated to match.
+func WithUnits(sys units.System) DecoderOption {
+	return func(d *Decoder) {
+		d.units = sys
+	}
+}
+
+type accumulator struct {
+	last  uint64
+	value uint64
+}
+
+// NewDecoder creates a Decoder over input.
+func NewDecoder(input io.Reader, opts ...DecoderOption) *Decoder {
+	d := &Decoder{
+		r:            NewReader(input),
+		accumulators: make(map[uint32]*accumulator),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Next decodes the next data message in the stream, returning io.EOF once
+// the input ends cleanly.
+func (d *Decoder) Next() (*Message, error) {
+	v, err := d.r.Next()
+	if err != nil {
+		return nil, err
+	}
+
+	mp := LookupProfile(v.Type)
+
+	m := &Message{Type: v.Type, Arch: v.Arch}
+	if mp != nil {
+		m.Name = mp.Name
+	}
+
+	// Every field shares a single copy of the message data
+	data := make([]byte, len(v.def.data))
+	copy(data, v.def.data)
+
+	order := byteOrder(v.Arch)
+	offset := 0
+
+	m.Fields = make([]Field, 0, len(v.def.Fields))
+	for _, fd := range v.def.Fields {
+		end := offset + int(fd.Size)
+		raw := data[offset:end:end]
+		offset = end
+
+		fp := mp.Field(fd.Number)
+
+		field := Field{Num: fd.Number, Type: fd.Type, Raw: raw}
+		if fp != nil {
+			field.Name = fp.Name
+			field.Units = fp.Units
+		}
+		field.Value = decodeValue(raw, fd.Type, order, fp)
+
+		m.Fields = append(m.Fields, field)
+	}
+
+	for _, fd := range v.def.DevFields {
+		end := offset + int(fd.Size)
+		m.DevFields = append(m.DevFields, DevField{DevDataIdx: fd.DevDataIdx, Num: fd.Number, Raw: data[offset:end:end]})
+		offset = end
+	}
+
+	// Resolve subfields now that the fields they refer to are decoded
+	for i := range m.Fields {
+		fp := mp.Field(m.Fields[i].Num)
+		if fp == nil || len(fp.Subfields) == 0 {
+			continue
+		}
+
+		if sub := fp.Resolve(m); sub != fp {
+			m.Fields[i].Name = sub.Name
+			m.Fields[i].Units = sub.Units
+			m.Fields[i].Value = decodeValue(m.Fields[i].Raw, m.Fields[i].Type, order, sub)
+		}
+	}
+
+	// Fields that are present in full reset their accumulators, in the units
+	// of the component that accumulates into them
+	for _, field := range m.Fields {
+		key := accumulatorKey(m.Type, field.Num)
+		scale, ok := accumulatedFields[key]
+		if !ok || field.Value == nil {
+			continue
+		}
+
+		values := floats(field.Value)
+		if len(values) == 0 || math.IsNaN(values[len(values)-1]) {
+			continue
+		}
+
+		bits := uint64(math.Round(values[len(values)-1] * scale))
+		d.accumulators[key] = &accumulator{last: bits, value: bits}
+	}
+
+	// Expand the components of the fields read from the file
+	for i, n := 0, len(m.Fields); i < n; i++ {
+		fp := mp.Field(m.Fields[i].Num)
+		if fp == nil || m.Fields[i].Value == nil {
+			continue
+		}
+
+		fp = fp.Resolve(m)
+		if len(fp.Components) == 0 {
+			continue
+		}
+
+		d.expand(m, mp, fp.Components, littleEndianBits(m.Fields[i].Raw, m.Fields[i].Type, order))
+	}
+
+	d.times.resolve(m, v.Compressed, v.TimeOffset)
+
+	if d.units != units.FIT {
+		convert(m, d.units)
+	}
+
+	return m, nil
+}
+
+// convert converts the numeric fields of m to the unit system sys. This is
+// done last as accumulators and component expansion work in profile units.
+func convert(m *Message, sys units.System) {
+	for i := range m.Fields {
+		f := &m.Fields[i]
+
+		switch v := f.Value.(type) {
+		case float64:
+			f.Value, f.Units = units.Convert(f.Name, v, f.Units, sys)
+		case []float64:
+			converted := make([]float64, len(v))
+			to := f.Units
+			for j := range v {
+				converted[j], to = units.Convert(f.Name, v[j], f.Units, sys)
+			}
+			f.Value, f.Units = converted, to
+		}
+	}
+}
+
+// expand unpacks components from the little endian bit string data into the
+// fields they target.
+func (d *Decoder) expand(m *Message, mp *MessageProfile, components []Component, data []byte) {
+	pos := uint(0)
+	for _, c := range components {
+		bits, ok := extractBits(data, pos, c.Bits)
+		if !ok {
+			return
+		}
+		pos += c.Bits
+
+		if c.Accumulate {
+			bits = d.accumulate(m.Type, c.Field, bits, c.Bits)
+		}
+		value := float64(bits)/c.scale() - c.Offset
+
+		// Values read from the file take precedence over expanded ones.
+		// Repeated components of the same field build up an array.
+		if i := m.fieldIndex(c.Field); i >= 0 {
+			if m.Fields[i].Raw == nil {
+				switch existing := m.Fields[i].Value.(type) {
+				case float64:
+					m.Fields[i].Value = []float64{existing, value}
+				case []float64:
+					m.Fields[i].Value = append(existing, value)
+				}
+			}
+			continue
+		}
+
+		field := Field{Num: c.Field, Value: value}
+		dp := mp.Field(c.Field)
+		if dp != nil {
+			field.Name = dp.Name
+			field.Units = dp.Units
+			field.Type = dp.Type
+			if dp.Array {
+				field.Value = []float64{value}
+			}
+		}
+		m.Fields = append(m.Fields, field)
+
+		// The destination may have components of its own, which are read
+		// from its value in its own units
+		if dp != nil && len(dp.Components) > 0 {
+			var buf [8]byte
+			binary.LittleEndian.PutUint64(buf[:], uint64(math.Round((value+dp.Offset)*dp.scale())))
+			d.expand(m, mp, dp.Components, buf[:])
+		}
+	}
+}
+
+// accumulate adds the difference between bits and the previous value of an
+// accumulated component, modulo its width, to the running total.
+func (d *Decoder) accumulate(mesgNum uint16, field byte, bits uint64, width uint) uint64 {
+	key := accumulatorKey(mesgNum, field)
+	acc := d.accumulators[key]
+	if acc == nil {
+		acc = &accumulator{}
+		d.accumulators[key] = acc
+	}
+
+	mask := uint64(1)<<width - 1
+	acc.value += (bits - acc.last) & mask
+	acc.last = bits
+
+	return acc.value
+}
+
+// accumulatedFields maps the fields accumulated components expand into to
+// the scale of the component.
+var accumulatedFields = func() map[uint32]float64 {
+	fields := make(map[uint32]float64)
+	for mesgNum, mp := range profile {
+		for _, fp := range mp.Fields {
+			for _, c := range fp.Components {
+				if c.Accumulate {
+					fields[accumulatorKey(mesgNum, c.Field)] = c.scale()
+				}
+			}
+		}
+	}
+	return fields
+}()
+
+func accumulatorKey(mesgNum uint16, field byte) uint32 {
+	return uint32(mesgNum)<<8 | uint32(field)
+}
+
+// fieldIndex returns the position of field num in m.Fields, or -1.
+func (m *Message) fieldIndex(num byte) int {
+	for i := range m.Fields {
+		if m.Fields[i].Num == num {
+			return i
+		}
+	}
+	return -1
+}
+
+// FieldNum returns field num, or nil if the message does not contain it.
+func (m *Message) FieldNum(num byte) *Field {
+	if i := m.fieldIndex(num); i >= 0 {
+		return &m.Fields[i]
+	}
+	return nil
+}
+
+// Field returns the field called name, or nil if the message does not
+// contain it. A field resolved to a subfield is found by either name.
+func (m *Message) Field(name string) *Field {
+	for i := range m.Fields {
+		if m.Fields[i].Name == name {
+			return &m.Fields[i]
+		}
+	}
+
+	mp := LookupProfile(m.Type)
+	for i := range m.Fields {
+		if fp := mp.Field(m.Fields[i].Num); fp != nil && fp.Name == name {
+			return &m.Fields[i]
+		}
+	}
+	return nil
+}
+
+// Float returns the value of a numeric field called name, and whether the
+// message contains a valid value for it. For arrays the first element is
+// returned.
+func (m *Message) Float(name string) (float64, bool) {
+	f := m.Field(name)
+	if f == nil {
+		return 0, false
+	}
+
+	switch v := f.Value.(type) {
+	case float64:
+		return v, true
+	case []float64:
+		if len(v) > 0 && !math.IsNaN(v[0]) {
+			return v[0], true
+		}
+	}
+	return 0, false
+}
+
+// Floats returns the values of a numeric field called name as a slice, or
+// nil if the message does not contain a valid value for it.
+func (m *Message) Floats(name string) []float64 {
+	f := m.Field(name)
+	if f == nil {
+		return nil
+	}
+	return floats(f.Value)
+}
+
+// Position returns the latitude and longitude of the position fields of m
+// called prefix+"_lat" and prefix+"_long" in degrees, whatever units the
+// message was decoded in. Records keep their position in "position" and
+// laps their start in "start_position".
+func (m *Message) Position(prefix string) (lat, long float64, ok bool) {
+	lat, lok := m.degrees(prefix + "_lat")
+	long, gok := m.degrees(prefix + "_long")
+	return lat, long, lok && gok
+}
+
+func (m *Message) degrees(name string) (float64, bool) {
+	v, ok := m.Float(name)
+	if !ok {
+		return 0, false
+	}
+	if m.Field(name).Units == "semicircles" {
+		v = units.Degrees(v)
+	}
+	return v, true
+}
+
+// String returns the value of a string field called name, and whether the
+// message contains a valid value for it. For arrays the first string is
+// returned.
+func (m *Message) String(name string) (string, bool) {
+	strs := m.Strings(name)
+	if len(strs) == 0 {
+		return "", false
+	}
+	return strs[0], true
+}
+
+// Strings returns the values of a string field called name as a slice, or
+// nil if the message does not contain a valid value for it.
+func (m *Message) Strings(name string) []string {
+	f := m.Field(name)
+	if f == nil {
+		return nil
+	}
+
+	switch v := f.Value.(type) {
+	case string:
+		return []string{v}
+	case []string:
+		return v
+	}
+	return nil
+}
+
+// Bytes returns the value of a byte array field called name, or nil if the
+// message does not contain a valid value for it.
+func (m *Message) Bytes(name string) []byte {
+	f := m.Field(name)
+	if f == nil {
+		return nil
+	}
+
+	v, _ := f.Value.([]byte)
+	return v
+}
+
+func floats(value interface{}) []float64 {
+	switch v := value.(type) {
+	case float64:
+		return []float64{v}
+	case []float64:
+		return v
+	}
+	return nil
+}
+
+// decodeValue decodes the raw bytes of a field of the given base type,
+// applying the scale and offset of its profile if it has one.
+func decodeValue(raw []byte, baseType byte, order binary.ByteOrder, fp *FieldProfile) interface{} {
+	switch baseType {
+	case TypeString:
+		strs := decodeStrings(raw)
+		if len(strs) == 0 {
+			return nil
+		}
+		if len(strs) == 1 && (fp == nil || !fp.Array) {
+			return strs[0]
+		}
+		return strs
+	case TypeByte:
+		// A byte array is only invalid if every byte is 0xFF
+		for _, b := range raw {
+			if b != 0xFF {
+				return raw
+			}
+		}
+		return nil
+	}
+
+	size := baseTypeSize(baseType)
+	n := len(raw) / size
+	if n == 0 {
+		return nil
+	}
+
+	scale, offset := 1.0, 0.0
+	array := n > 1
+	if fp != nil {
+		scale, offset = fp.scale(), fp.Offset
+		array = array || fp.Array
+	}
+
+	if !array {
+		value, ok := elementValue(raw, baseType, order)
+		if !ok {
+			return nil
+		}
+		return value/scale - offset
+	}
+
+	values := make([]float64, n)
+	valid := false
+	for i := range values {
+		value, ok := elementValue(raw[i*size:(i+1)*size], baseType, order)
+		if !ok {
+			values[i] = math.NaN()
+			continue
+		}
+		values[i] = value/scale - offset
+		valid = true
+	}
+	if !valid {
+		return nil
+	}
+
+	return values
+}
+
+// decodeStrings splits a string field into its null terminated strings. A
+// field normally holds one string padded with nulls, but string arrays are
+// stored back to back. Trailing empty strings are padding and are dropped,
+// as is any 0xFF padding. Invalid UTF-8 is replaced with U+FFFD.
+func decodeStrings(raw []byte) []string {
+	raw = bytes.TrimRight(raw, "\xff")
+
+	var strs []string
+	for len(raw) > 0 {
+		s := raw
+		if i := bytes.IndexByte(raw, 0); i >= 0 {
+			s, raw = raw[:i], raw[i+1:]
+		} else {
+			raw = nil
+		}
+		strs = append(strs, strings.ToValidUTF8(string(s), "\uFFFD"))
+	}
+
+	for len(strs) > 0 && strs[len(strs)-1] == "" {
+		strs = strs[:len(strs)-1]
+	}
+	return strs
+}
+
+// elementBits reads a single value of size bytes.
+func elementBits(b []byte, order binary.ByteOrder) uint64 {
+	switch len(b) {
+	case 1:
+		return uint64(b[0])
+	case 2:
+		return uint64(order.Uint16(b))
+	case 4:
+		return uint64(order.Uint32(b))
+	case 8:
+		return order.Uint64(b)
+	}
+	return 0
+}
+
+// elementValue converts a single value of a base type to a float64, and
+// reports whether it is valid.
+func elementValue(b []byte, baseType byte, order binary.ByteOrder) (float64, bool) {
+	bits := elementBits(b[:baseTypeSize(baseType)], order)
+	if int(baseType) < len(baseTypeInvalid) && bits == baseTypeInvalid[baseType] {
+		return 0, false
+	}
+
+	switch baseType {
+	case TypeSint8:
+		return float64(int8(bits)), true
+	case TypeSint16:
+		return float64(int16(bits)), true
+	case TypeSint32:
+		return float64(int32(bits)), true
+	case TypeSint64:
+		return float64(int64(bits)), true
+	case TypeFloat32:
+		return float64(math.Float32frombits(uint32(bits))), true
+	case TypeFloat64:
+		return math.Float64frombits(bits), true
+	}
+	return float64(bits), true
+}
+
+// littleEndianBits returns the raw bytes of a field with each element in
+// little endian order, so components can be read from it as one bit string.
+func littleEndianBits(raw []byte, baseType byte, order binary.ByteOrder) []byte {
+	size := baseTypeSize(baseType)
+	if order == binary.LittleEndian || size == 1 {
+		return raw
+	}
+
+	data := make([]byte, len(raw))
+	for i := 0; i+size <= len(raw); i += size {
+		for j := 0; j < size; j++ {
+			data[i+j] = raw[i+size-1-j]
+		}
+	}
+	return data
+}
+
+// extractBits reads width bits starting at bit pos of data, least
+// significant bit first.
+func extractBits(data []byte, pos, width uint) (uint64, bool) {
+	if pos+width > uint(len(data))*8 {
+		return 0, false
+	}
+
+	var value uint64
+	for i := uint(0); i < width; i++ {
+		bit := pos + i
+		if (data[bit/8]>>(bit%8))&1 == 1 {
+			value |= 1 << i
+		}
+	}
+	return value, true
+}
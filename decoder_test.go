@@ -0,0 +1,372 @@
+package gofit
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+	"testing"
+
+	"github.com/kcfwpi/gofit/units"
+)
+
+// testDefinition builds a definition record. Each field is given as its
+// number, size and base type number.
+func testDefinition(local, arch byte, mesgNum uint16, fields ...[3]byte) []byte {
+	rec := []byte{0x40 | local, 0, arch, 0, 0, byte(len(fields))}
+	if arch == 0 {
+		binary.LittleEndian.PutUint16(rec[3:5], mesgNum)
+	} else {
+		binary.BigEndian.PutUint16(rec[3:5], mesgNum)
+	}
+
+	for _, f := range fields {
+		baseType := f[2]
+		if baseTypeSize(baseType) > 1 {
+			baseType |= 0x80
+		}
+		rec = append(rec, f[0], f[1], baseType)
+	}
+
+	return rec
+}
+
+// testData builds a data record from the concatenated field values.
+func testData(local byte, values ...[]byte) []byte {
+	rec := []byte{local}
+	for _, v := range values {
+		rec = append(rec, v...)
+	}
+	return rec
+}
+
+// testFile wraps records in a file header and trailing CRC.
+func testFile(records ...[]byte) []byte {
+	var data []byte
+	for _, rec := range records {
+		data = append(data, rec...)
+	}
+
+	file := []byte{14, 0x10, 0, 0, 0, 0, 0, 0, '.', 'F', 'I', 'T', 0, 0}
+	binary.LittleEndian.PutUint32(file[4:8], uint32(len(data)))
+	file = append(file, data...)
+
+	return append(file, 0, 0)
+}
+
+func le16(v uint16) []byte {
+	b := make([]byte, 2)
+	binary.LittleEndian.PutUint16(b, v)
+	return b
+}
+
+func le32(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint32(b, v)
+	return b
+}
+
+func decodeAll(t *testing.T, data []byte) []*Message {
+	var messages []*Message
+
+	d := NewDecoder(bytes.NewReader(data))
+	for {
+		m, err := d.Next()
+		if err == io.EOF {
+			return messages
+		}
+		if err != nil {
+			t.Fatalf("%s\n", err)
+		}
+		messages = append(messages, m)
+	}
+}
+
+func TestDecoderRecords(t *testing.T) {
+	data, err := os.ReadFile("testfiles/test2.fit")
+	if err != nil {
+		t.Fatalf("%s\n", err)
+	}
+
+	n := 0
+	for _, m := range decodeAll(t, data) {
+		if m.Type != MesgRecord {
+			continue
+		}
+		n++
+
+		if m.Name != "record" {
+			t.Errorf("got name %q", m.Name)
+		}
+
+		raw := m.FieldNum(7).Raw
+		power, ok := m.Float("power")
+		if !ok || power != float64(binary.LittleEndian.Uint16(raw)) {
+			t.Errorf("got power %f, raw %v", power, raw)
+		}
+
+		// Speed expands into enhanced_speed with the same scale
+		speed, sok := m.Float("speed")
+		enhanced, eok := m.Float("enhanced_speed")
+		if sok != eok || speed != enhanced {
+			t.Errorf("got speed %f and enhanced_speed %f", speed, enhanced)
+		}
+		if f := m.Field("enhanced_speed"); f != nil && (f.Raw != nil || f.Units != "m/s") {
+			t.Errorf("enhanced_speed should be an expanded field in m/s")
+		}
+
+		if altitude, ok := m.Float("altitude"); ok {
+			raw := m.Field("altitude").Raw
+			if math.Abs(altitude-(float64(binary.LittleEndian.Uint16(raw))/5-500)) > 1e-9 {
+				t.Errorf("got altitude %f for %v", altitude, raw)
+			}
+		}
+	}
+
+	if n != 1309 {
+		t.Errorf("got %d records", n)
+	}
+}
+
+func TestDecoderCompressedSpeedDistance(t *testing.T) {
+	// 12 bits of speed in 1/100 m/s then 12 bits of distance in 1/16 m
+	packed := func(speed, distance uint32) []byte {
+		v := speed | distance<<12
+		return []byte{byte(v), byte(v >> 8), byte(v >> 16)}
+	}
+
+	data := testFile(
+		testDefinition(0, 0, MesgRecord, [3]byte{8, 3, TypeByte}),
+		testData(0, packed(500, 4000)),
+		testData(0, packed(510, 100)),
+		testData(0, packed(520, 200)),
+	)
+
+	distances := []float64{4000.0 / 16, 4196.0 / 16, 4296.0 / 16}
+	speeds := []float64{5, 5.1, 5.2}
+
+	for i, m := range decodeAll(t, data) {
+		distance, dok := m.Float("distance")
+		speed, sok := m.Float("speed")
+		if !dok || !sok || distance != distances[i] || math.Abs(speed-speeds[i]) > 1e-9 {
+			t.Errorf("message %d: got speed %f distance %f", i, speed, distance)
+		}
+
+		// Speed expands again into enhanced_speed
+		if enhanced, ok := m.Float("enhanced_speed"); !ok || math.Abs(enhanced-speeds[i]) > 1e-9 {
+			t.Errorf("message %d: got enhanced_speed %f", i, enhanced)
+		}
+	}
+}
+
+func TestDecoderAccumulatedCycles(t *testing.T) {
+	data := testFile(
+		testDefinition(0, 0, MesgRecord, [3]byte{18, 1, TypeUint8}),
+		testDefinition(1, 0, MesgRecord, [3]byte{19, 4, TypeUint32}),
+		testData(0, []byte{250}),
+		testData(0, []byte{5}),
+		testData(1, le32(1000)),
+		testData(0, []byte{10}),
+	)
+
+	totals := []float64{250, 261, 1000, 1034}
+	for i, m := range decodeAll(t, data) {
+		if total, ok := m.Float("total_cycles"); !ok || total != totals[i] {
+			t.Errorf("message %d: got total_cycles %f, want %f", i, total, totals[i])
+		}
+	}
+}
+
+func TestDecoderEventData16(t *testing.T) {
+	data := testFile(
+		testDefinition(0, 0, MesgEvent, [3]byte{0, 1, TypeEnum}, [3]byte{2, 2, TypeUint16}),
+		testData(0, []byte{0}, le16(1234)),
+	)
+
+	m := decodeAll(t, data)[0]
+	if v, ok := m.Float("data"); !ok || v != 1234 {
+		t.Errorf("got data %f", v)
+	}
+}
+
+func TestDecoderEventTimestamp12(t *testing.T) {
+	// Ten 12 bit timestamps in 1/1024 s, packed least significant bit first
+	stamps := []uint64{1024, 2048, 3000, 4095, 100, 1124, 2148, 3172, 4000, 10}
+	packed := make([]byte, 15)
+	for i, s := range stamps {
+		for b := 0; b < 12; b++ {
+			if s>>uint(b)&1 == 1 {
+				bit := i*12 + b
+				packed[bit/8] |= 1 << uint(bit%8)
+			}
+		}
+	}
+
+	data := testFile(
+		testDefinition(0, 0, MesgHr, [3]byte{10, 15, TypeByte}),
+		testData(0, packed),
+	)
+
+	m := decodeAll(t, data)[0]
+	got := m.Floats("event_timestamp")
+	want := []float64{1, 2, 3000.0 / 1024, 4095.0 / 1024, 4196.0 / 1024, 5220.0 / 1024, 6244.0 / 1024, 7268.0 / 1024, 8096.0 / 1024, 8202.0 / 1024}
+	if len(got) != len(want) {
+		t.Fatalf("got %v", got)
+	}
+	for i := range want {
+		if math.Abs(got[i]-want[i]) > 1e-9 {
+			t.Errorf("timestamp %d: got %f, want %f", i, got[i], want[i])
+		}
+	}
+}
+
+func TestDecoderInvalid(t *testing.T) {
+	data := testFile(
+		testDefinition(0, 0, MesgRecord, [3]byte{3, 1, TypeUint8}, [3]byte{7, 2, TypeUint16}),
+		testData(0, []byte{0xFF}, le16(250)),
+	)
+
+	m := decodeAll(t, data)[0]
+	if _, ok := m.Float("heart_rate"); ok {
+		t.Errorf("invalid heart rate should not have a value")
+	}
+	if f := m.Field("heart_rate"); f == nil || f.Value != nil || len(f.Raw) != 1 {
+		t.Errorf("invalid fields should be kept with their raw bytes")
+	}
+	if power, ok := m.Float("power"); !ok || power != 250 {
+		t.Errorf("got power %f", power)
+	}
+}
+
+func TestDecoderStrings(t *testing.T) {
+	str := func(s string, size int) []byte {
+		b := make([]byte, size)
+		copy(b, s)
+		return b
+	}
+
+	data := testFile(
+		testDefinition(0, 0, MesgFileCreator, [3]byte{0, 2, TypeUint16}),
+		testDefinition(1, 0, MesgDeviceInfo, [3]byte{27, 16, TypeString}, [3]byte{19, 8, TypeString}),
+		testData(1, str("Edge 530", 16), str("a\xffb", 8)),
+		testData(1, append([]byte("Edge"), 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF), str("", 8)),
+		testDefinition(2, 0, MesgFieldDescription, [3]byte{3, 16, TypeString}, [3]byte{8, 8, TypeString}),
+		testData(2, str("Power\x00Leistung", 16), str("W", 8)),
+		testDefinition(3, 0, MesgDeveloperDataID, [3]byte{0, 4, TypeByte}),
+		testData(3, []byte{0xFF, 0xFF, 0xFF, 0xFF}),
+		testData(3, []byte{0, 0xFF, 1, 0xFF}),
+	)
+
+	messages := decodeAll(t, data)
+
+	if s, ok := messages[0].String("product_name"); !ok || s != "Edge 530" {
+		t.Errorf("got product name %q", s)
+	}
+	if s, ok := messages[0].String("descriptor"); !ok || s != "a�b" {
+		t.Errorf("invalid UTF-8 should be replaced, got %q", s)
+	}
+	if s, ok := messages[1].String("product_name"); !ok || s != "Edge" {
+		t.Errorf("0xFF padding should be removed, got %q", s)
+	}
+	if f := messages[1].Field("descriptor"); f == nil || f.Value != nil {
+		t.Errorf("empty strings should be invalid")
+	}
+
+	if names := messages[2].Strings("field_name"); len(names) != 2 || names[0] != "Power" || names[1] != "Leistung" {
+		t.Errorf("got field names %q", names)
+	}
+	if units, ok := messages[2].Field("units").Value.([]string); !ok || len(units) != 1 || units[0] != "W" {
+		t.Errorf("string arrays should decode to []string, got %v", messages[2].Field("units").Value)
+	}
+
+	if b := messages[3].Bytes("developer_id"); b != nil {
+		t.Errorf("byte arrays of 0xFF should be invalid, got %v", b)
+	}
+	if b := messages[4].Bytes("developer_id"); !bytes.Equal(b, []byte{0, 0xFF, 1, 0xFF}) {
+		t.Errorf("got developer id %v", b)
+	}
+}
+
+func TestDecoderDeveloperFieldNames(t *testing.T) {
+	data, err := os.ReadFile("testfiles/devdata.fit")
+	if err != nil {
+		t.Fatalf("%s\n", err)
+	}
+
+	names := make(map[string]string)
+	for _, m := range decodeAll(t, data) {
+		if m.Type == MesgFieldDescription {
+			name, _ := m.String("field_name")
+			units, _ := m.String("units")
+			names[name] = units
+		}
+	}
+
+	if units, ok := names["Vertical Oscillation"]; !ok || units != "Centimeters" {
+		t.Errorf("got developer fields %q", names)
+	}
+}
+
+func TestDecoderUnits(t *testing.T) {
+	data, err := os.ReadFile("testfiles/test2.fit")
+	if err != nil {
+		t.Fatalf("%s\n", err)
+	}
+
+	fit := decodeAll(t, data)
+
+	imperial := []*Message{}
+	d := NewDecoder(bytes.NewReader(data), WithUnits(units.Imperial))
+	for {
+		m, err := d.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("%s\n", err)
+		}
+		imperial = append(imperial, m)
+	}
+
+	checked := 0
+	for i, m := range fit {
+		if m.Type != MesgRecord {
+			continue
+		}
+
+		speed, ok := m.Float("speed")
+		if !ok {
+			continue
+		}
+		if mph, _ := imperial[i].Float("speed"); !near(mph, units.MilesPerHour(speed)) || imperial[i].Field("speed").Units != "mph" {
+			t.Errorf("record %d: got %f mph for %f m/s", i, mph, speed)
+		}
+		if dist, ok := m.Float("distance"); ok {
+			if miles, _ := imperial[i].Float("distance"); !near(miles, units.Miles(dist)) {
+				t.Errorf("record %d: got %f miles for %f m", i, miles, dist)
+			}
+		}
+
+		lat, long, ok := m.Position("position")
+		ilat, ilong, iok := imperial[i].Position("position")
+		if ok != iok || lat != ilat || long != ilong {
+			t.Errorf("record %d: positions differ", i)
+		}
+		if ok && (lat < -90 || lat > 90 || long < -180 || long > 180) {
+			t.Errorf("record %d: got position %f, %f", i, lat, long)
+		}
+		checked++
+	}
+
+	if checked == 0 {
+		t.Errorf("no records converted")
+	}
+	if !imperial[0].Time.Equal(fit[0].Time) {
+		t.Errorf("times should not be converted")
+	}
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
@@ -0,0 +1,384 @@
+package gofit
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+)
+
+// Protocol and profile versions written to the headers of encoded files
+const (
+	encodeProtocolVersion = 0x20
+	encodeProfileVersion  = 2132
+)
+
+// Encoder writes decoded messages back out as a FIT file. Messages are
+// buffered until Close, which writes the file header with the final data
+// size, the messages and the file CRC.
+//
+// Each message is encoded from the raw bytes of its fields, so values are
+// written exactly as they were read. Fields expanded from components are
+// not written, except a timestamp reconstructed from a compressed header or
+// a timestamp_16, which is written as a full timestamp. Developer fields
+// are written as they were read, so the developer_data_id and
+// field_description messages describing them must be encoded first.
+type Encoder struct {
+	w    io.Writer
+	data bytes.Buffer
+
+	// Definition currently held by each local message type, and the local
+	// message type to replace next
+	local [16]string
+	next  int
+
+	closed bool
+}
+
+// NewEncoder creates an Encoder writing to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// Encode adds m to the file, preceded by a definition message if the local
+// message types do not already hold one for its layout.
+func (e *Encoder) Encode(m *Message) error {
+	if e.closed {
+		return errors.New("encoder closed")
+	}
+
+	order := byteOrder(m.Arch)
+
+	// Build the definition and the data of the message together
+	var def, data bytes.Buffer
+	numFields := 0
+	for _, f := range m.Fields {
+		raw := f.Raw
+		if raw == nil {
+			// Only a reconstructed timestamp is worth keeping
+			if f.Num != 253 || m.fieldIndexRaw(253) >= 0 {
+				continue
+			}
+			v, ok := f.Value.(float64)
+			if !ok {
+				continue
+			}
+			raw = make([]byte, 4)
+			order.PutUint32(raw, uint32(v))
+			f.Type = TypeUint32
+		}
+
+		if len(raw) == 0 || len(raw) > 255 {
+			return errors.New("invalid fit file: field size out of range")
+		}
+
+		baseType := f.Type
+		if baseTypeSize(f.Type) > 1 {
+			baseType |= 0x80
+		}
+		def.Write([]byte{f.Num, byte(len(raw)), baseType})
+		data.Write(raw)
+		numFields++
+	}
+	if numFields > 255 || len(m.DevFields) > 255 {
+		return errors.New("invalid fit file: too many fields")
+	}
+
+	if len(m.DevFields) > 0 {
+		def.WriteByte(byte(len(m.DevFields)))
+		for _, f := range m.DevFields {
+			if len(f.Raw) == 0 || len(f.Raw) > 255 {
+				return errors.New("invalid fit file: field size out of range")
+			}
+			def.Write([]byte{f.Num, byte(len(f.Raw)), f.DevDataIdx})
+			data.Write(f.Raw)
+		}
+	}
+
+	header := make([]byte, 5)
+	header[1] = m.Arch
+	order.PutUint16(header[2:4], m.Type)
+	header[4] = byte(numFields)
+
+	// Field definitions are three bytes each, so a definition with developer
+	// fields never has the same signature as one without
+	signature := string(header) + def.String()
+
+	local := -1
+	for i, s := range e.local {
+		if s == signature {
+			local = i
+			break
+		}
+	}
+
+	if local < 0 {
+		local = e.next
+		e.next = (e.next + 1) % len(e.local)
+		e.local[local] = signature
+
+		recordHeader := 0x40 | byte(local)
+		if len(m.DevFields) > 0 {
+			recordHeader |= 0x20
+		}
+		e.data.WriteByte(recordHeader)
+		e.data.Write(header)
+		e.data.Write(def.Bytes())
+	}
+
+	e.data.WriteByte(byte(local))
+	e.data.Write(data.Bytes())
+
+	return nil
+}
+
+// fieldIndexRaw returns the position of field num in m.Fields if it was read
+// from the file, or -1.
+func (m *Message) fieldIndexRaw(num byte) int {
+	for i := range m.Fields {
+		if m.Fields[i].Num == num && m.Fields[i].Raw != nil {
+			return i
+		}
+	}
+	return -1
+}
+
+// Close writes the file to the underlying writer. It does not close the
+// writer itself.
+func (e *Encoder) Close() error {
+	if e.closed {
+		return errors.New("encoder closed")
+	}
+	e.closed = true
+
+	if uint64(e.data.Len()) > math.MaxUint32 {
+		return errors.New("invalid fit file: data too large")
+	}
+
+	header := make([]byte, 14)
+	header[0] = 14
+	header[1] = encodeProtocolVersion
+	binary.LittleEndian.PutUint16(header[2:4], encodeProfileVersion)
+	binary.LittleEndian.PutUint32(header[4:8], uint32(e.data.Len()))
+	copy(header[8:12], ".FIT")
+	binary.LittleEndian.PutUint16(header[12:14], CRC(header[:12]))
+
+	crc := updateCRC(CRC(header), e.data.Bytes())
+
+	if _, err := e.w.Write(header); err != nil {
+		return err
+	}
+	if _, err := e.w.Write(e.data.Bytes()); err != nil {
+		return err
+	}
+	_, err := e.w.Write([]byte{byte(crc), byte(crc >> 8)})
+	return err
+}
+
+// WriteActivity encodes every message of a as a FIT file written to w.
+func WriteActivity(w io.Writer, a *Activity) error {
+	e := NewEncoder(w)
+	for _, m := range a.Messages {
+		if err := e.Encode(m); err != nil {
+			return err
+		}
+	}
+	return e.Close()
+}
+
+// SetFloat sets the numeric field called name to v, given in the units of
+// the FIT profile, re-encoding the raw bytes of the field so that the change
+// is kept when the message is encoded. NaN sets the field's invalid value.
+// For arrays the first element is set. Values out of the range of the
+// field's type are clamped to it. It returns false if the message has no
+// numeric field called name read from the file.
+func (m *Message) SetFloat(name string, v float64) bool {
+	f := m.Field(name)
+	if f == nil || f.Raw == nil || f.Type == TypeString || f.Type == TypeByte || int(f.Type) >= len(baseTypeSizes) {
+		return false
+	}
+
+	size := baseTypeSize(f.Type)
+	if len(f.Raw) < size {
+		return false
+	}
+
+	fp := LookupProfile(m.Type).Field(f.Num)
+	if fp != nil {
+		fp = fp.Resolve(m)
+	}
+
+	scale, offset := 1.0, 0.0
+	if fp != nil {
+		scale, offset = fp.scale(), fp.Offset
+	}
+
+	order := byteOrder(m.Arch)
+	putElement(f.Raw[:size], f.Type, order, (v+offset)*scale)
+	f.Value = decodeValue(f.Raw, f.Type, order, fp)
+
+	return true
+}
+
+// addFloat sets the numeric field called name to v as SetFloat does, adding
+// the field as the FIT profile defines it if it was not read from the file.
+// It returns an error if the profile has no numeric field called name for
+// the message, or the message holds it with another type.
+func (m *Message) addFloat(name string, v float64) error {
+	if m.SetFloat(name, v) {
+		return nil
+	}
+
+	var num byte
+	var fp *FieldProfile
+	if mp := LookupProfile(m.Type); mp != nil {
+		for n, p := range mp.Fields {
+			if p.Name == name {
+				num, fp = n, p
+				break
+			}
+		}
+	}
+	if fp == nil {
+		for n, p := range commonFields {
+			if p.Name == name {
+				num, fp = n, p
+				break
+			}
+		}
+	}
+	if fp == nil || fp.Type == TypeString || fp.Type == TypeByte || int(fp.Type) >= len(baseTypeSizes) {
+		return errors.New("cannot set field " + name + ": not a numeric field of the message")
+	}
+
+	f := Field{Num: num, Name: fp.Name, Units: fp.Units, Type: fp.Type, Raw: make([]byte, baseTypeSize(fp.Type))}
+
+	// A field expanded from components is replaced by one written to the file
+	if i := m.fieldIndex(num); i >= 0 {
+		if m.Fields[i].Raw != nil {
+			return errors.New("cannot set field " + name + ": unexpected type")
+		}
+		m.Fields[i] = f
+	} else {
+		m.Fields = append(m.Fields, f)
+	}
+
+	m.SetFloat(name, v)
+	return nil
+}
+
+// putElement writes a single value of a base type, rounding it for integer
+// types and keeping it clear of the type's invalid value.
+func putElement(b []byte, baseType byte, order binary.ByteOrder, value float64) {
+	var bits uint64
+
+	switch {
+	case math.IsNaN(value):
+		bits = baseTypeInvalid[baseType]
+	case baseType == TypeFloat32:
+		bits = uint64(math.Float32bits(float32(value)))
+	case baseType == TypeFloat64:
+		bits = math.Float64bits(value)
+	case baseType == TypeSint8 || baseType == TypeSint16 || baseType == TypeSint32 || baseType == TypeSint64:
+		// The invalid value is the largest one
+		width := uint(8 * len(b))
+		max := int64(baseTypeInvalid[baseType]) - 1
+		min := -max - 2
+
+		value = math.Round(value)
+		switch {
+		case value >= float64(max):
+			bits = uint64(max)
+		case value <= float64(min):
+			bits = uint64(min)
+		default:
+			bits = uint64(int64(value))
+		}
+		if width < 64 {
+			bits &= 1<<width - 1
+		}
+	default:
+		// The invalid value is either all ones or zero
+		width := uint(8 * len(b))
+		max := uint64(math.MaxUint64)
+		if width < 64 {
+			max = 1<<width - 1
+		}
+		min := uint64(0)
+		if baseTypeInvalid[baseType] == 0 {
+			min = 1
+		} else {
+			max--
+		}
+
+		value = math.Round(value)
+		switch {
+		case value >= float64(max):
+			bits = max
+		case value <= float64(min):
+			bits = min
+		default:
+			bits = uint64(value)
+		}
+	}
+
+	switch len(b) {
+	case 1:
+		b[0] = byte(bits)
+	case 2:
+		order.PutUint16(b, uint16(bits))
+	case 4:
+		order.PutUint32(b, uint32(bits))
+	case 8:
+		order.PutUint64(b, bits)
+	}
+}
+
+// clearRaw sets a field read from the file to the invalid value of its
+// type: every element of a numeric field, an empty string, or a byte array
+// of 0xFF.
+func clearRaw(f *Field, order binary.ByteOrder) {
+	if f.Raw == nil {
+		return
+	}
+
+	switch {
+	case f.Type == TypeString:
+		for i := range f.Raw {
+			f.Raw[i] = 0
+		}
+	case f.Type == TypeByte || int(f.Type) >= len(baseTypeSizes):
+		for i := range f.Raw {
+			f.Raw[i] = 0xFF
+		}
+	default:
+		size := baseTypeSize(f.Type)
+		for i := 0; i+size <= len(f.Raw); i += size {
+			putElement(f.Raw[i:i+size], f.Type, order, math.NaN())
+		}
+	}
+	f.Value = nil
+}
+
+// clone returns a deep copy of m whose raw bytes can be changed without
+// affecting m.
+func (m *Message) clone() *Message {
+	c := *m
+	c.Fields = make([]Field, len(m.Fields))
+	for i, f := range m.Fields {
+		if f.Raw != nil {
+			f.Raw = append([]byte(nil), f.Raw...)
+			if _, ok := f.Value.([]byte); ok {
+				f.Value = f.Raw
+			}
+		}
+		c.Fields[i] = f
+	}
+	c.DevFields = make([]DevField, len(m.DevFields))
+	for i, f := range m.DevFields {
+		f.Raw = append([]byte(nil), f.Raw...)
+		c.DevFields[i] = f
+	}
+	return &c
+}
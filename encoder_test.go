@@ -0,0 +1,132 @@
+package gofit
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestEncoderRoundTrip(t *testing.T) {
+	for path, data := range readTestFiles(t) {
+		if path == "testfiles/bad.fit" {
+			continue
+		}
+
+		messages := decodeAll(t, data)
+
+		var buf bytes.Buffer
+		e := NewEncoder(&buf)
+		for _, m := range messages {
+			if err := e.Encode(m); err != nil {
+				t.Fatalf("%s: %s", path, err)
+			}
+		}
+		if err := e.Close(); err != nil {
+			t.Fatalf("%s: %s", path, err)
+		}
+
+		encoded := buf.Bytes()
+		if size := binary.LittleEndian.Uint32(encoded[4:8]); int(size) != len(encoded)-16 {
+			t.Errorf("%s: got data size %d for %d bytes", path, size, len(encoded))
+		}
+		if crc := CRC(encoded); crc != 0 {
+			t.Errorf("%s: got file CRC %04x", path, crc)
+		}
+
+		decoded := decodeAll(t, encoded)
+		if len(decoded) != len(messages) {
+			t.Fatalf("%s: got %d messages, want %d", path, len(decoded), len(messages))
+		}
+		for i, m := range messages {
+			got := decoded[i]
+			if got.Type != m.Type || !got.Time.Equal(m.Time) {
+				t.Fatalf("%s: message %d: got %s at %s, want %s at %s", path, i, got.Name, got.Time, m.Name, m.Time)
+			}
+			// Compared as text since invalid array elements are NaN
+			for _, f := range m.Fields {
+				if f.Raw == nil {
+					continue
+				}
+				if g := got.FieldNum(f.Num); g == nil || fmt.Sprint(g.Value) != fmt.Sprint(f.Value) {
+					t.Fatalf("%s: message %d: field %s changed", path, i, f.Name)
+				}
+			}
+			if !reflect.DeepEqual(got.DevFields, m.DevFields) {
+				t.Fatalf("%s: message %d: developer fields changed", path, i)
+			}
+		}
+	}
+}
+
+func TestEncoderLocalTypes(t *testing.T) {
+	// More layouts than local message types, in a message type with no
+	// profile so values are unscaled
+	var buf bytes.Buffer
+	e := NewEncoder(&buf)
+	for i := 0; i < 40; i++ {
+		m := &Message{Type: 0xFF00, Fields: []Field{{Num: byte(i % 20), Type: TypeUint16, Raw: le16(uint16(i))}}}
+		if err := e.Encode(m); err != nil {
+			t.Fatalf("%s\n", err)
+		}
+	}
+	if err := e.Close(); err != nil {
+		t.Fatalf("%s\n", err)
+	}
+
+	for i, m := range decodeAll(t, buf.Bytes()) {
+		if f := m.FieldNum(byte(i % 20)); f == nil || f.Value != float64(i) {
+			t.Errorf("message %d: got %v", i, m.Fields)
+		}
+	}
+}
+
+func TestSetFloat(t *testing.T) {
+	data := testFile(
+		testDefinition(0, 0, MesgRecord, [3]byte{253, 4, TypeUint32}, [3]byte{2, 2, TypeUint16}, [3]byte{13, 1, TypeSint8}),
+		testData(0, le32(1000000000), le16(2600), []byte{20}),
+	)
+	m := decodeAll(t, data)[0]
+
+	// Altitude has a scale of 5 and an offset of 500
+	if !m.SetFloat("altitude", 12.2) {
+		t.Fatalf("could not set altitude")
+	}
+	if v, _ := m.Float("altitude"); math.Abs(v-12.2) > 1e-9 {
+		t.Errorf("got altitude %f", v)
+	}
+
+	// Clamped clear of the invalid value
+	m.SetFloat("temperature", 1000)
+	if v, _ := m.Float("temperature"); v != 126 {
+		t.Errorf("got temperature %f", v)
+	}
+	m.SetFloat("temperature", math.NaN())
+	if _, ok := m.Float("temperature"); ok {
+		t.Errorf("expected invalid temperature")
+	}
+
+	if m.SetFloat("heart_rate", 100) {
+		t.Errorf("set a missing field")
+	}
+	if err := m.addFloat("heart_rate", 100); err != nil {
+		t.Errorf("%s\n", err)
+	}
+	if err := m.addFloat("no_such_field", 1); err == nil {
+		t.Errorf("expected an error for a field not in the profile")
+	}
+
+	var buf bytes.Buffer
+	e := NewEncoder(&buf)
+	e.Encode(m)
+	e.Close()
+	encoded := decodeAll(t, buf.Bytes())[0]
+	if v, _ := encoded.Float("altitude"); math.Abs(v-12.2) > 1e-9 {
+		t.Errorf("got encoded altitude %f", v)
+	}
+	if v, _ := encoded.Float("heart_rate"); v != 100 {
+		t.Errorf("got encoded heart rate %f", v)
+	}
+}
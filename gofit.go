@@ -40,6 +40,61 @@ func GetEpoch() time.Time {
 	return time.Date(1989, time.December, 31, 0, 0, 0, 0, time.UTC)
 }
 
+// byteOrder returns the byte order of multi-byte values in messages defined
+// with the given architecture.
+func byteOrder(arch byte) binary.ByteOrder {
+	if arch == 0 {
+		return binary.LittleEndian
+	}
+	return binary.BigEndian
+}
+
+// ByteOrder returns the byte order of the message's multi-byte field values,
+// as set by the architecture of its definition.
+func (m DataMessage) ByteOrder() binary.ByteOrder {
+	return byteOrder(m.Arch)
+}
+
+// Uint16 returns field num decoded in the message's byte order, and false if
+// the message has no such field or it is too short.
+func (m DataMessage) Uint16(num byte) (uint16, bool) {
+	if len(m.Fields[num]) < 2 {
+		return 0, false
+	}
+	return m.ByteOrder().Uint16(m.Fields[num]), true
+}
+
+// Uint32 returns field num decoded in the message's byte order, and false if
+// the message has no such field or it is too short.
+func (m DataMessage) Uint32(num byte) (uint32, bool) {
+	if len(m.Fields[num]) < 4 {
+		return 0, false
+	}
+	return m.ByteOrder().Uint32(m.Fields[num]), true
+}
+
+// Int16 returns signed field num decoded in the message's byte order.
+func (m DataMessage) Int16(num byte) (int16, bool) {
+	v, ok := m.Uint16(num)
+	return int16(v), ok
+}
+
+// Int32 returns signed field num decoded in the message's byte order.
+func (m DataMessage) Int32(num byte) (int32, bool) {
+	v, ok := m.Uint32(num)
+	return int32(v), ok
+}
+
+// StringField returns string field num with its null padding removed, and
+// false if the message has no valid string in that field.
+func (m DataMessage) StringField(num byte) (string, bool) {
+	strs := decodeStrings(m.Fields[num])
+	if len(strs) == 0 {
+		return "", false
+	}
+	return strs[0], true
+}
+
 func NewFIT(input io.Reader) *FIT {
 	fit := FIT{input: input}
 	fit.MessageChan = make(chan DataMessage)
@@ -47,8 +102,8 @@ func NewFIT(input io.Reader) *FIT {
 	return &fit
 }
 
-func (f *FIT) parseFieldDefinitions(defMesg *DefinitionMesg, fieldDefs []byte) error {
-	defMesg.Fields = make([]FieldDefinition, 0)
+func parseFieldDefinitions(defMesg *DefinitionMesg, fieldDefs []byte) error {
+	defMesg.Fields = defMesg.Fields[:0]
 
 	for i := 0; i < len(fieldDefs); i++ {
 		fd := FieldDefinition{}
@@ -69,7 +124,7 @@ func (f *FIT) parseFieldDefinitions(defMesg *DefinitionMesg, fieldDefs []byte) e
 		if (fieldDefs[i] & 64) == 64 {
 			fd.Endian = true
 		}
-		fd.Type = fieldDefs[i] & 15
+		fd.Type = fieldDefs[i] & 31
 
 		defMesg.Fields = append(defMesg.Fields, fd)
 	}
@@ -77,8 +132,8 @@ func (f *FIT) parseFieldDefinitions(defMesg *DefinitionMesg, fieldDefs []byte) e
 	return nil
 }
 
-func (f *FIT) parseDevFieldDefinitions(defMesg *DefinitionMesg, fieldDefs []byte) error {
-	defMesg.DevFields = make([]FieldDefinition, 0)
+func parseDevFieldDefinitions(defMesg *DefinitionMesg, fieldDefs []byte) error {
+	defMesg.DevFields = defMesg.DevFields[:0]
 
 	for i := 0; i < len(fieldDefs); i++ {
 		fd := FieldDefinition{}
@@ -104,241 +159,22 @@ func (f *FIT) parseDevFieldDefinitions(defMesg *DefinitionMesg, fieldDefs []byte
 	return nil
 }
 
-func (f *FIT) parseDataMessage(defMesg *DefinitionMesg) (DataMessage, int, error) {
-	dataMsg := DataMessage{}
-	dataMsg.Type = defMesg.MesgNum
-
-	totalRead := 0
-
-	//fmt.Printf("%d\n", defMesg.MesgNum)
-
-	dataMsg.Fields = make(map[byte][]byte)
-	dataMsg.DevFields = make(map[byte]map[byte][]byte)
-	dataMsg.Arch = defMesg.Arch
-
-	for _, field := range defMesg.Fields {
-		dataMsg.Fields[field.Number] = make([]byte, field.Size)
-		br, derr := f.input.Read(dataMsg.Fields[field.Number])
-		if derr != nil || br <= 0 {
-			return dataMsg, totalRead, derr
-		}
-		totalRead += br
-	}
-
-	for _, field := range defMesg.DevFields {
-		if dataMsg.DevFields[field.DevDataIdx] == nil {
-			dataMsg.DevFields[field.DevDataIdx] = make(map[byte][]byte)
-		}
-
-		dataMsg.DevFields[field.DevDataIdx][field.Number] = make([]byte, field.Size)
-		br, derr := f.input.Read(dataMsg.DevFields[field.DevDataIdx][field.Number])
-		if derr != nil || br <= 0 {
-			return dataMsg, totalRead, derr
-		}
-		totalRead += br
-
-	}
-
-	return dataMsg, totalRead, nil
-}
-
 func (f *FIT) Parse() {
 	go f.parse()
 }
 
 func (f *FIT) parse() {
-	for true {
-		totalDataRead := 0
-
-		// Parse the header
-		headerLen := make([]byte, 1)
-		br, re := f.input.Read(headerLen)
-		if re != nil || br <= 0 {
-			f.MessageChan <- DataMessage{Error: re}
-			close(f.MessageChan)
-			return
-		}
-
-		protocolVersion := make([]byte, 1)
-		br, re = f.input.Read(protocolVersion)
-		if re != nil || br <= 0 {
-			f.MessageChan <- DataMessage{Error: re}
-			close(f.MessageChan)
-			return
-		}
+	r := NewReader(f.input)
 
-		profileVersion := make([]byte, 2)
-		br, re = f.input.Read(profileVersion)
-		if re != nil || br <= 0 {
-			f.MessageChan <- DataMessage{Error: re}
-			close(f.MessageChan)
-			return
-		}
-
-		dataSize := make([]byte, 4)
-		br, re = f.input.Read(dataSize)
-		if re != nil || br <= 0 {
-			f.MessageChan <- DataMessage{Error: re}
-			close(f.MessageChan)
-			return
-		}
-		dataSizeInt := binary.LittleEndian.Uint32(dataSize)
-
-		// Seek ahead past the header now that we know its length
-		header := make([]byte, headerLen[0]-8)
-		br, re = f.input.Read(header)
-		if re != nil || br <= 0 {
-			f.MessageChan <- DataMessage{Error: re}
+	for true {
+		dataMsg, err := r.Next()
+		if err != nil {
+			f.MessageChan <- DataMessage{Error: err}
 			close(f.MessageChan)
 			return
 		}
 
-		// Declare what you can up front to avoid unnecessary gc
-		recordHeader := make([]byte, 1)
-		reserved := make([]byte, 1)
-		arch := make([]byte, 1)
-		globalMsgNum := make([]byte, 2)
-		numFields := make([]byte, 1)
-		numDevFields := make([]byte, 1)
-
-		localMessageTypes := make(map[byte]DefinitionMesg)
-
-		for uint32(totalDataRead) < dataSizeInt {
-			// Read the record header
-			br, re = f.input.Read(recordHeader)
-			if re != nil || br <= 0 {
-				f.MessageChan <- DataMessage{Error: re}
-				close(f.MessageChan)
-				return
-			}
-			totalDataRead += br
-
-			// If this is a definition message
-			if (recordHeader[0] & 64) == 64 {
-				currentDefinition := DefinitionMesg{}
-				currentDefinition.DevDataFlag = recordHeader[0] & 32
-
-				localMessageType := recordHeader[0] & 15
-
-				// Read the reserved
-				br, re = f.input.Read(reserved)
-				if re != nil || br <= 0 {
-					f.MessageChan <- DataMessage{Error: re}
-					close(f.MessageChan)
-					return
-				}
-				totalDataRead += br
-
-				br, re = f.input.Read(arch)
-				if re != nil || br <= 0 {
-					f.MessageChan <- DataMessage{Error: re}
-					close(f.MessageChan)
-					return
-				}
-				totalDataRead += br
-				currentDefinition.Arch = arch[0]
-
-				// Read the global message number
-				br, re = f.input.Read(globalMsgNum)
-				if re != nil || br <= 0 {
-					f.MessageChan <- DataMessage{Error: re}
-					close(f.MessageChan)
-					return
-				}
-				totalDataRead += br
-
-				// Check the arch field to determine the endianness of the global mesg num
-				if int64(arch[0]) == 0 {
-					currentDefinition.MesgNum = binary.LittleEndian.Uint16(globalMsgNum)
-				} else {
-					currentDefinition.MesgNum = binary.BigEndian.Uint16(globalMsgNum)
-				}
-
-				// Read the number of fields
-				br, re = f.input.Read(numFields)
-				if re != nil || br <= 0 {
-					f.MessageChan <- DataMessage{Error: re}
-					close(f.MessageChan)
-					return
-				}
-				totalDataRead += br
-
-				// Read the full block of field definitions and then parse them
-				if numFields[0] != 0 {
-					fieldDefinitions := make([]byte, 3*int(numFields[0]))
-					br, re := f.input.Read(fieldDefinitions)
-					if re != nil || br <= 0 {
-						f.MessageChan <- DataMessage{Error: re}
-						close(f.MessageChan)
-						return
-					}
-					totalDataRead += br
-
-					pfd := f.parseFieldDefinitions(&currentDefinition, fieldDefinitions)
-					if pfd != nil {
-						f.MessageChan <- DataMessage{Error: pfd}
-						close(f.MessageChan)
-						return
-					}
-				}
-
-				// If the developer data flag is set, read the dev data fields
-				if currentDefinition.DevDataFlag > 0 {
-					// Read the number of fields
-					br, re = f.input.Read(numDevFields)
-					if re != nil || br <= 0 {
-						f.MessageChan <- DataMessage{Error: re}
-						close(f.MessageChan)
-						return
-					}
-					totalDataRead += br
-
-					devFieldDefinitions := make([]byte, 3*numDevFields[0])
-					br, re := f.input.Read(devFieldDefinitions)
-					// if re != nil || br <= 0 {
-					if re != nil {
-						f.MessageChan <- DataMessage{Error: re}
-						close(f.MessageChan)
-						return
-					}
-					totalDataRead += br
-
-					pfd := f.parseDevFieldDefinitions(&currentDefinition, devFieldDefinitions)
-					if pfd != nil {
-						f.MessageChan <- DataMessage{Error: pfd}
-						close(f.MessageChan)
-						return
-					}
-
-				}
-
-				// Add this local message type to map
-				localMessageTypes[localMessageType] = currentDefinition
-			} else {
-				// Parse the local message type of this data message then look for its definition in the map
-				localMessageType := recordHeader[0] & 15
-				currentDefinition := localMessageTypes[localMessageType]
-
-				// Now parse the data msg
-				dataMsg, dataMsgBr, dataErr := f.parseDataMessage(&currentDefinition)
-				if dataErr != nil {
-					f.MessageChan <- DataMessage{Error: dataErr}
-					close(f.MessageChan)
-					return
-				}
-				totalDataRead += dataMsgBr
-
-				// And send the result to the result channel
-				f.MessageChan <- dataMsg
-			}
-		}
-
-		crc := make([]byte, 2)
-		br, re = f.input.Read(crc)
-		if re != nil || br <= 0 {
-			f.MessageChan <- DataMessage{Error: re}
-			close(f.MessageChan)
-			return
-		}
+		// The view is only valid until the next read so send a copy
+		f.MessageChan <- dataMsg.DataMessage()
 	}
 }
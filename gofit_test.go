@@ -5,6 +5,7 @@ import (
 	"encoding/binary"
 	"math"
 	"os"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -56,7 +57,7 @@ func TestPower(t *testing.T) {
 				adjustedTs := GetEpoch().Add(time.Duration(ts) * time.Second)
 
 				if (powers[idx] != power) || (times[idx] != adjustedTs.Unix()) {
-					t.Logf("index: %d, powers: %d, ts: %d, message: %d\n", idx, powers[idx], power)
+					t.Logf("index: %d, powers: %d, power: %d, times: %d, ts: %d\n", idx, powers[idx], power, times[idx], adjustedTs.Unix())
 					t.Fail()
 				}
 				idx++
@@ -200,12 +201,203 @@ func TestDevData(t *testing.T) {
 		}
 
 		if m.Type == 20 {
-			for i, j := range m.DevFields {
-				if devFieldMap[i] == "Vertical Oscillation" {
-					t.Logf("!! %s - %f !!\n", devFieldMap[i], math.Float32frombits(binary.LittleEndian.Uint32(j)))
+			for _, fields := range m.DevFields {
+				for i, j := range fields {
+					if devFieldMap[i] == "Vertical Oscillation" {
+						t.Logf("!! %s - %f !!\n", devFieldMap[i], math.Float32frombits(binary.LittleEndian.Uint32(j)))
+					}
 				}
 			}
 			t.Logf("-----\n")
 		}
 	}
 }
+
+// toBigEndian rewrites a little endian FIT file so that every definition
+// declares the big endian architecture, swapping the bytes of the global
+// message numbers and multi-byte field values to match. Developer fields are
+// left as they are.
+func toBigEndian(t *testing.T, data []byte) []byte {
+	out := append([]byte{}, data...)
+
+	type layout struct {
+		fields    []FieldDefinition
+		devFields int
+	}
+	defs := make(map[byte]layout)
+
+	pos := int(out[0])
+	end := pos + int(binary.LittleEndian.Uint32(out[4:8]))
+
+	for pos < end {
+		header := out[pos]
+		pos++
+
+		if header&128 == 128 || header&64 == 0 {
+			local := header & 15
+			if header&128 == 128 {
+				local = (header >> 5) & 3
+			}
+
+			def, ok := defs[local]
+			if !ok {
+				t.Fatalf("undefined local message type %d", local)
+			}
+			for _, fd := range def.fields {
+				size := baseTypeSize(fd.Type)
+				for i := pos; size > 1 && i+size <= pos+int(fd.Size); i += size {
+					for j := 0; j < size/2; j++ {
+						out[i+j], out[i+size-1-j] = out[i+size-1-j], out[i+j]
+					}
+				}
+				pos += int(fd.Size)
+			}
+			pos += def.devFields
+			continue
+		}
+
+		if out[pos+1] != 0 {
+			t.Fatalf("expected a little endian file")
+		}
+		out[pos+1] = 1
+		out[pos+2], out[pos+3] = out[pos+3], out[pos+2]
+
+		def := DefinitionMesg{}
+		numFields := int(out[pos+4])
+		pos += 5
+		if err := parseFieldDefinitions(&def, out[pos:pos+3*numFields]); err != nil {
+			t.Fatalf("%s\n", err)
+		}
+		pos += 3 * numFields
+
+		devFields := 0
+		if header&32 == 32 {
+			numDevFields := int(out[pos])
+			pos++
+			for i := 0; i < numDevFields; i++ {
+				devFields += int(out[pos+3*i+1])
+			}
+			pos += 3 * numDevFields
+		}
+
+		defs[header&15] = layout{fields: def.Fields, devFields: devFields}
+	}
+
+	return out
+}
+
+func equalValues(a, b interface{}) bool {
+	af, aok := a.([]float64)
+	bf, bok := b.([]float64)
+	if !aok || !bok {
+		return reflect.DeepEqual(a, b)
+	}
+
+	if len(af) != len(bf) {
+		return false
+	}
+	for i := range af {
+		if af[i] != bf[i] && !(math.IsNaN(af[i]) && math.IsNaN(bf[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestBigEndianDecoding(t *testing.T) {
+	for _, path := range []string{"testfiles/test.fit", "testfiles/test2.fit", "testfiles/fit2.fit", "testfiles/devdata.fit"} {
+		little, ferr := os.ReadFile(path)
+		if ferr != nil {
+			t.Fatalf("%s\n", ferr)
+		}
+		big := toBigEndian(t, little)
+
+		if bytes.Equal(little, big) {
+			t.Fatalf("%s: nothing was swapped", path)
+		}
+
+		lm := decodeAll(t, little)
+		bm := decodeAll(t, big)
+		if len(lm) != len(bm) {
+			t.Fatalf("%s: got %d messages, want %d", path, len(bm), len(lm))
+		}
+
+		for i := range lm {
+			l, b := lm[i], bm[i]
+			if b.Arch != 1 || l.Type != b.Type || l.Name != b.Name || !l.Time.Equal(b.Time) || len(l.Fields) != len(b.Fields) {
+				t.Fatalf("%s: message %d differs", path, i)
+			}
+
+			for j := range l.Fields {
+				lf, bf := l.Fields[j], b.Fields[j]
+				if lf.Num != bf.Num || lf.Name != bf.Name || lf.Units != bf.Units || !equalValues(lf.Value, bf.Value) {
+					t.Errorf("%s: message %d field %s: got %v, want %v", path, i, lf.Name, bf.Value, lf.Value)
+				}
+			}
+		}
+	}
+}
+
+func TestBigEndianDataMessage(t *testing.T) {
+	little, ferr := os.ReadFile("testfiles/test2.fit")
+	if ferr != nil {
+		t.Fatalf("%s\n", ferr)
+	}
+
+	powers := func(data []byte) []uint16 {
+		var p []uint16
+		fit := NewFIT(bytes.NewReader(data))
+		fit.Parse()
+		for m := range fit.MessageChan {
+			if m.Type == 20 {
+				power, ok := m.Uint16(7)
+				if !ok {
+					t.Fatalf("missing power")
+				}
+				p = append(p, power)
+			}
+		}
+		return p
+	}
+
+	want := powers(little)
+	if got := powers(toBigEndian(t, little)); !reflect.DeepEqual(got, want) || len(want) != 1309 {
+		t.Errorf("big endian powers differ")
+	}
+}
+
+func TestStringField(t *testing.T) {
+	f, ferr := os.Open("testfiles/devdata.fit")
+	if ferr != nil {
+		t.Fatalf("%s\n", ferr)
+	}
+	defer f.Close()
+
+	fit := NewFIT(f)
+	fit.Parse()
+
+	var names []string
+	for m := range fit.MessageChan {
+		if m.Error != nil {
+			break
+		}
+		if m.Type == 206 {
+			name, ok := m.StringField(3)
+			if !ok {
+				t.Errorf("field description without a name")
+			}
+			names = append(names, name)
+		}
+	}
+
+	found := false
+	for _, name := range names {
+		found = found || name == "Vertical Oscillation"
+	}
+	if !found {
+		t.Errorf("got field names %q", names)
+	}
+	if _, ok := (DataMessage{Fields: map[byte][]byte{3: {0, 0, 0}}}).StringField(3); ok {
+		t.Errorf("empty string should not have a value")
+	}
+}
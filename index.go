@@ -0,0 +1,155 @@
+package gofit
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"sort"
+	"time"
+)
+
+// IndexEntry locates a single data message within a FIT file.
+type IndexEntry struct {
+	// Offset of the message's record header from the start of the file
+	Offset int64
+	Type   uint16
+
+	// The definition in effect for the message. Entries decoded with the
+	// same definition message share it.
+	Definition *DefinitionMesg
+
+	// Value of the message's timestamp field, or the zero time if it has none
+	Timestamp time.Time
+}
+
+// Index records the location of every data message in a FIT file so that
+// individual messages can be read back through an io.ReaderAt without
+// decoding the whole file again.
+type Index struct {
+	input   io.ReaderAt
+	Entries []IndexEntry
+
+	// Positions in Entries of the messages of each type
+	byType map[uint16][]int
+}
+
+// BuildIndex reads the size bytes of input once and indexes every data
+// message found.
+func BuildIndex(input io.ReaderAt, size int64) (*Index, error) {
+	idx := &Index{input: input, byType: make(map[uint16][]int)}
+
+	r := NewReader(bufio.NewReader(io.NewSectionReader(input, 0, size)))
+
+	// Copy each definition once, the Reader reuses its own
+	definitions := make(map[*localDefinition]*DefinitionMesg)
+	ids := make(map[*localDefinition]int)
+
+	// Compressed timestamp headers are relative to the last full timestamp
+	var last uint32
+	valid := false
+
+	for {
+		m, err := r.Next()
+		if err == io.EOF {
+			return idx, nil
+		}
+		if err != nil {
+			return idx, err
+		}
+
+		if ids[m.def] != m.def.id {
+			def := m.def.DefinitionMesg
+			def.Fields = append([]FieldDefinition(nil), def.Fields...)
+			def.DevFields = append([]FieldDefinition(nil), def.DevFields...)
+
+			definitions[m.def] = &def
+			ids[m.def] = m.def.id
+		}
+
+		entry := IndexEntry{Offset: m.Offset, Type: m.Type, Definition: definitions[m.def]}
+		if ts := m.Field(253); len(ts) == 4 {
+			if seconds := byteOrder(m.Arch).Uint32(ts); seconds != 0xFFFFFFFF {
+				last = seconds
+				valid = true
+				entry.Timestamp = timeOf(last)
+			}
+		} else if m.Compressed && valid {
+			last += (uint32(m.TimeOffset) - last) & 0x1F
+			entry.Timestamp = timeOf(last)
+		}
+
+		idx.byType[m.Type] = append(idx.byType[m.Type], len(idx.Entries))
+		idx.Entries = append(idx.Entries, entry)
+	}
+}
+
+// Read decodes the data message of entry i.
+func (idx *Index) Read(i int) (DataMessage, error) {
+	if i < 0 || i >= len(idx.Entries) {
+		return DataMessage{}, errors.New("index out of range")
+	}
+	entry := idx.Entries[i]
+
+	// Skip the record header
+	data := make([]byte, entry.Definition.dataSize())
+	if _, err := idx.input.ReadAt(data, entry.Offset+1); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return DataMessage{}, err
+	}
+
+	return newDataMessage(entry.Definition, data), nil
+}
+
+// Count returns the number of messages of type mesgNum in the file.
+func (idx *Index) Count(mesgNum uint16) int {
+	return len(idx.byType[mesgNum])
+}
+
+// Nth decodes the nth message of type mesgNum, counting from zero. For
+// example Nth(19, 2) returns the third lap.
+func (idx *Index) Nth(mesgNum uint16, n int) (DataMessage, error) {
+	positions := idx.byType[mesgNum]
+	if n < 0 || n >= len(positions) {
+		return DataMessage{}, errors.New("index out of range")
+	}
+
+	return idx.Read(positions[n])
+}
+
+// Between decodes the messages of type mesgNum with timestamps in the range
+// [start, end). Messages of a type are expected to be in time order, as
+// records are.
+func (idx *Index) Between(mesgNum uint16, start, end time.Time) ([]DataMessage, error) {
+	positions := idx.byType[mesgNum]
+
+	first := sort.Search(len(positions), func(i int) bool {
+		return !idx.Entries[positions[i]].Timestamp.Before(start)
+	})
+	last := sort.Search(len(positions), func(i int) bool {
+		return !idx.Entries[positions[i]].Timestamp.Before(end)
+	})
+
+	messages := make([]DataMessage, 0, last-first)
+	for _, i := range positions[first:last] {
+		m, err := idx.Read(i)
+		if err != nil {
+			return messages, err
+		}
+		messages = append(messages, m)
+	}
+
+	return messages, nil
+}
+
+// Start returns the earliest timestamp in the file.
+func (idx *Index) Start() time.Time {
+	var start time.Time
+	for _, entry := range idx.Entries {
+		if !entry.Timestamp.IsZero() && (start.IsZero() || entry.Timestamp.Before(start)) {
+			start = entry.Timestamp
+		}
+	}
+	return start
+}
@@ -0,0 +1,120 @@
+package gofit
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestIndexRead(t *testing.T) {
+	for path, data := range readTestFiles(t) {
+		if path == "testfiles/bad.fit" {
+			continue
+		}
+
+		idx, err := BuildIndex(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Errorf("%s: %s", path, err)
+			continue
+		}
+
+		fit := NewFIT(bytes.NewReader(data))
+		fit.Parse()
+
+		i := 0
+		for want := range fit.MessageChan {
+			if want.Error != nil {
+				continue
+			}
+
+			got, rerr := idx.Read(i)
+			if rerr != nil {
+				t.Errorf("%s: message %d: %s", path, i, rerr)
+			} else if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: message %d differs", path, i)
+			}
+			i++
+		}
+
+		if i != len(idx.Entries) {
+			t.Errorf("%s: indexed %d messages, want %d", path, len(idx.Entries), i)
+		}
+	}
+}
+
+func TestIndexBetween(t *testing.T) {
+	f, ferr := os.Open("testfiles/test2.fit")
+	if ferr != nil {
+		t.Fatalf("%s\n", ferr)
+	}
+	defer f.Close()
+
+	info, serr := f.Stat()
+	if serr != nil {
+		t.Fatalf("%s\n", serr)
+	}
+
+	idx, err := BuildIndex(f, info.Size())
+	if err != nil {
+		t.Fatalf("%s\n", err)
+	}
+
+	start := time.Unix(1431558100, 0)
+	if !idx.Start().Equal(start) {
+		t.Errorf("got start %s, want %s", idx.Start(), start)
+	}
+
+	records, err := idx.Between(20, start.Add(10*time.Second), start.Add(20*time.Second))
+	if err != nil {
+		t.Fatalf("%s\n", err)
+	}
+	if len(records) != 10 {
+		t.Fatalf("got %d records, want 10", len(records))
+	}
+
+	// The powers at 10s through 19s, as in TestPower
+	powers := []uint16{352, 342, 222, 302, 302, 280, 295, 306, 276, 304}
+	for i, m := range records {
+		if power := byteOrder(m.Arch).Uint16(m.Fields[7]); power != powers[i] {
+			t.Errorf("record %d: got power %d, want %d", i, power, powers[i])
+		}
+	}
+}
+
+func TestIndexNth(t *testing.T) {
+	data, ferr := os.ReadFile("testfiles/test.fit")
+	if ferr != nil {
+		t.Fatalf("%s\n", ferr)
+	}
+
+	idx, err := BuildIndex(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("%s\n", err)
+	}
+
+	var laps []DataMessage
+	fit := NewFIT(bytes.NewReader(data))
+	fit.Parse()
+	for m := range fit.MessageChan {
+		if m.Type == 19 {
+			laps = append(laps, m)
+		}
+	}
+
+	if idx.Count(19) != len(laps) || len(laps) == 0 {
+		t.Fatalf("got %d laps, want %d", idx.Count(19), len(laps))
+	}
+
+	for n, want := range laps {
+		got, nerr := idx.Nth(19, n)
+		if nerr != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("lap %d differs: %v", n, nerr)
+		}
+	}
+
+	if _, nerr := idx.Nth(19, len(laps)); nerr == nil {
+		t.Errorf("expected an error past the last lap")
+	}
+}
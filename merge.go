@@ -0,0 +1,201 @@
+package gofit
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// Messages describing the athlete, their settings or the file that later
+// activities in a merge repeat, and that are only kept from the first
+var mergeSetupMessages = map[uint16]bool{
+	MesgFileID:      true,
+	MesgFileCreator: true,
+	MesgUserProfile: true,
+	MesgZonesTarget: true,
+	MesgHrZone:      true,
+	MesgPowerZone:   true,
+	MesgSpeedZone:   true,
+	MesgCadenceZone: true,
+	MesgSport:       true,
+	MesgSoftware:    true,
+	MesgWorkout:     true,
+}
+
+// Merge combines activities recorded one after the other, such as a ride
+// split in two by a device restart, into a single activity. The activities
+// are taken in order of their start times and their messages concatenated,
+// dropping records that overlap ones already merged.
+//
+// The file_id and other setup messages are kept from the first activity
+// only, and device_info messages once per device. Developer data ids and
+// field descriptions are kept once per developer data index and field, so
+// activities that use the same index for different applications cannot be
+// merged faithfully. Record distances continue on from those of earlier
+// activities, laps are renumbered, and consecutive sessions of the same
+// sport are combined into one whose totals are recomputed as Trim does.
+// The activity message of the last activity is kept and updated to match.
+// time_in_zone messages are dropped.
+//
+// The activities themselves are not changed, and the merged activity can
+// be written with WriteActivity.
+func Merge(activities ...*Activity) (*Activity, error) {
+	sorted := make([]*Activity, 0, len(activities))
+	for _, a := range activities {
+		if len(a.Records) > 0 {
+			sorted = append(sorted, a)
+		}
+	}
+	if len(sorted) == 0 {
+		return nil, errors.New("no records to merge")
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].StartTime().Before(sorted[j].StartTime())
+	})
+
+	merged := &Activity{}
+	seen := make(map[string]bool)
+	setup := make(map[uint16]bool)
+
+	var sessions, summaries []*Message
+	var lastRecord time.Time
+	var lastDistance float64
+	distanceOK := false
+
+	for _, a := range sorted {
+		// Distances continue from the last merged record
+		offset := 0.0
+		if distanceOK {
+			for _, r := range a.Records {
+				if d, ok := r.Float("distance"); ok {
+					offset = lastDistance - d
+					break
+				}
+			}
+		}
+
+		for _, m := range a.Messages {
+			switch m.Type {
+			case MesgSession:
+				sessions = append(sessions, m)
+				continue
+			case MesgActivity:
+				summaries = append(summaries, m)
+				continue
+			case MesgTimeInZone:
+				continue
+			case MesgDeviceInfo, MesgDeveloperDataID, MesgFieldDescription:
+				if seen[mergeKey(m)] {
+					continue
+				}
+			}
+
+			if mergeSetupMessages[m.Type] && setup[m.Type] {
+				continue
+			}
+
+			c := m.clone()
+			if m.Type == MesgRecord {
+				if !lastRecord.IsZero() && !m.Time.After(lastRecord) {
+					continue
+				}
+				lastRecord = m.Time
+
+				if d, ok := c.Float("distance"); ok {
+					if err := c.addFloat("distance", d+offset); err != nil {
+						return nil, err
+					}
+					lastDistance, distanceOK = d+offset, true
+				}
+			}
+			merged.add(c)
+		}
+
+		// Only now can later activities' messages be recognised as repeats,
+		// as an activity may hold several of a type or for a device
+		for _, m := range a.Messages {
+			setup[m.Type] = true
+			switch m.Type {
+			case MesgDeviceInfo, MesgDeveloperDataID, MesgFieldDescription:
+				seen[mergeKey(m)] = true
+			}
+		}
+	}
+
+	first, last := merged.Records[0].Time, merged.Records[len(merged.Records)-1].Time
+
+	for i, lap := range merged.Laps {
+		if err := lap.addFloat("message_index", float64(i)); err != nil {
+			return nil, err
+		}
+	}
+
+	// Combine consecutive sessions of the same sport, totalling what is only
+	// known as a total before the session is recomputed
+	totals := []string{"total_timer_time", "total_calories", "total_cycles"}
+	var combined *Message
+	sums := make([]float64, len(totals))
+	finish := func() {
+		if combined == nil {
+			return
+		}
+		for i, name := range totals {
+			combined.SetFloat(name, sums[i])
+		}
+		merged.add(combined)
+	}
+	for _, s := range sessions {
+		if combined == nil || !sameSport(combined, s) {
+			finish()
+			combined = s.clone()
+			for i := range sums {
+				sums[i] = math.NaN()
+			}
+		}
+		setTime(combined, s.Time)
+
+		for i, name := range totals {
+			if v, ok := s.Float(name); ok {
+				if math.IsNaN(sums[i]) {
+					sums[i] = 0
+				}
+				sums[i] += v
+			}
+		}
+	}
+	finish()
+
+	if len(summaries) > 0 {
+		merged.add(summaries[len(summaries)-1].clone())
+	}
+
+	merged.summarizeSessions(NewTimer(merged.Messages, 0), first, last)
+
+	return merged, nil
+}
+
+// mergeKey identifies the device or developer data a message describes.
+func mergeKey(m *Message) string {
+	key := fmt.Sprint(m.Type)
+	for _, name := range []string{"device_index", "manufacturer", "product", "serial_number", "developer_data_index", "field_definition_number"} {
+		if v, ok := m.Float(name); ok {
+			key += fmt.Sprintf(" %s=%v", name, v)
+		}
+	}
+	return key
+}
+
+// sameSport reports whether two sessions are of the same sport and sub
+// sport.
+func sameSport(a, b *Message) bool {
+	for _, name := range []string{"sport", "sub_sport"} {
+		va, oka := a.Float(name)
+		vb, okb := b.Float(name)
+		if oka != okb || va != vb {
+			return false
+		}
+	}
+	return true
+}
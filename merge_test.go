@@ -0,0 +1,140 @@
+package gofit
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	a := readActivity(t, "testfiles/test2.fit")
+	records := a.Records
+
+	// Split the ride in two as a device restart would, then write and read
+	// each part back as its own file
+	var parts []*Activity
+	for _, r := range [][2]int{{0, 600}, {601, len(records) - 1}} {
+		part, err := a.Trim(records[r[0]].Time, records[r[1]].Time)
+		if err != nil {
+			t.Fatalf("%s\n", err)
+		}
+
+		var buf bytes.Buffer
+		if err := WriteActivity(&buf, part); err != nil {
+			t.Fatalf("%s\n", err)
+		}
+		if part, err = ReadActivity(&buf); err != nil {
+			t.Fatalf("%s\n", err)
+		}
+		parts = append(parts, part)
+	}
+
+	// Out of order on purpose
+	merged, err := Merge(parts[1], parts[0])
+	if err != nil {
+		t.Fatalf("%s\n", err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteActivity(&buf, merged); err != nil {
+		t.Fatalf("%s\n", err)
+	}
+	if crc := CRC(buf.Bytes()); crc != 0 {
+		t.Errorf("got file CRC %04x", crc)
+	}
+	b, err := ReadActivity(&buf)
+	if err != nil {
+		t.Fatalf("%s\n", err)
+	}
+
+	if len(b.Records) != len(records) || !b.Records[0].Time.Equal(records[0].Time) {
+		t.Fatalf("got %d records from %s", len(b.Records), b.Records[0].Time)
+	}
+	if b.FileID == nil || len(b.Sessions) != 1 || len(b.Laps) != 2 || len(b.DeviceInfos) != len(a.DeviceInfos) {
+		t.Fatalf("got %d sessions, %d laps and %d device infos", len(b.Sessions), len(b.Laps), len(b.DeviceInfos))
+	}
+	for i, lap := range b.Laps {
+		if index, _ := lap.Float("message_index"); index != float64(i) {
+			t.Errorf("lap %d has index %f", i, index)
+		}
+	}
+	n := 0
+	for _, m := range b.Messages {
+		if m.Type == MesgFileID {
+			n++
+		}
+	}
+	if n != 1 {
+		t.Errorf("got %d file ids", n)
+	}
+
+	// Distances continue across the restart
+	d0, _ := records[0].Float("distance")
+	d1, _ := records[len(records)-1].Float("distance")
+	want := d1 - d0
+	if got, _ := b.Records[len(b.Records)-1].Float("distance"); math.Abs(got-want) > 50 {
+		t.Errorf("got last distance %f, want about %f", got, want)
+	}
+
+	s := b.Sessions[0]
+	if got, _ := s.Float("total_distance"); math.Abs(got-want) > 50 {
+		t.Errorf("got total distance %f, want about %f", got, want)
+	}
+	if laps, _ := s.Float("num_laps"); laps != 2 {
+		t.Errorf("got %f laps", laps)
+	}
+	for _, name := range []string{"total_elapsed_time", "total_timer_time", "total_calories"} {
+		want, _ := a.Sessions[0].Float(name)
+		if got, _ := s.Float(name); math.Abs(got-want) > 0.02*want {
+			t.Errorf("got %s %f, want %f", name, got, want)
+		}
+	}
+	if sessions, _ := b.Activity.Float("num_sessions"); sessions != 1 || !b.Activity.Time.Equal(records[len(records)-1].Time) {
+		t.Errorf("got activity with %f sessions at %s", sessions, b.Activity.Time)
+	}
+}
+
+func TestMergeAddsFields(t *testing.T) {
+	a := readActivity(t, "testfiles/test2.fit")
+	records := a.Records
+
+	var parts []*Activity
+	for _, r := range [][2]int{{0, 600}, {601, len(records) - 1}} {
+		part, err := a.Trim(records[r[0]].Time, records[r[1]].Time)
+		if err != nil {
+			t.Fatalf("%s\n", err)
+		}
+		stripFields(part)
+		parts = append(parts, part)
+	}
+
+	merged, err := Merge(parts...)
+	if err != nil {
+		t.Fatalf("%s\n", err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteActivity(&buf, merged); err != nil {
+		t.Fatalf("%s\n", err)
+	}
+	b, err := ReadActivity(&buf)
+	if err != nil {
+		t.Fatalf("%s\n", err)
+	}
+
+	for i, lap := range b.Laps {
+		if index, ok := lap.Float("message_index"); !ok || index != float64(i) {
+			t.Errorf("lap %d has index %f", i, index)
+		}
+	}
+	d, _ := parts[0].Records[len(parts[0].Records)-1].Float("distance")
+	if got, _ := b.Records[601].Float("distance"); got < d {
+		t.Errorf("distance went back from %f to %f", d, got)
+	}
+}
+
+func TestMergeNothing(t *testing.T) {
+	if _, err := Merge(); err == nil {
+		t.Errorf("expected an error")
+	}
+}
@@ -0,0 +1,128 @@
+package gofit
+
+import (
+	"io"
+	"math"
+	"strings"
+
+	"github.com/kcfwpi/gofit/units"
+)
+
+// Mean radius of the earth in meters
+const earthRadius = 6371008.8
+
+// PrivacyZone is a circle around a place such as home or work within which
+// positions are removed.
+type PrivacyZone struct {
+	// Centre of the zone in degrees
+	Lat, Long float64
+
+	// Radius of the zone in meters
+	Radius float64
+}
+
+// Contains reports whether the position lat, long in degrees is within the
+// zone.
+func (z PrivacyZone) Contains(lat, long float64) bool {
+	return distance(z.Lat, z.Long, lat, long) <= z.Radius
+}
+
+// Privacy describes how positions are obscured before a file is shared.
+// Positions within any of the zones are removed. The rest are moved by the
+// shift and then rounded to the grid, if these are set.
+type Privacy struct {
+	Zones []PrivacyZone
+
+	// Degrees added to every latitude and longitude
+	ShiftLat, ShiftLong float64
+
+	// Spacing in degrees of the grid positions are rounded to, or 0 to
+	// leave them unrounded. 0.001 is about 100 m.
+	Grid float64
+}
+
+// Apply obscures every position in m, which must be decoded in the units
+// of the FIT profile. Positions are pairs of semicircle fields called
+// prefix+"_lat" and prefix+"_long", such as a record's position_lat and
+// position_long or a lap's start_position_lat and start_position_long, so
+// the bounding boxes of sessions and the positions of other messages are
+// obscured too. Every other field is left as it was. It returns the number
+// of positions removed.
+func (p Privacy) Apply(m *Message) int {
+	removed := 0
+
+	for i := range m.Fields {
+		f := &m.Fields[i]
+		if f.Units != "semicircles" || f.Raw == nil || !strings.HasSuffix(f.Name, "_lat") {
+			continue
+		}
+
+		prefix := strings.TrimSuffix(f.Name, "_lat")
+		lat, long, ok := m.Position(prefix)
+		if !ok {
+			continue
+		}
+
+		remove := false
+		for _, z := range p.Zones {
+			if z.Contains(lat, long) {
+				remove = true
+				break
+			}
+		}
+		if remove {
+			m.SetFloat(prefix+"_lat", math.NaN())
+			m.SetFloat(prefix+"_long", math.NaN())
+			removed++
+			continue
+		}
+
+		lat += p.ShiftLat
+		long += p.ShiftLong
+		if p.Grid > 0 {
+			lat = math.Round(lat/p.Grid) * p.Grid
+			long = math.Round(long/p.Grid) * p.Grid
+		}
+
+		// Keep the position on the globe
+		lat = math.Max(-90, math.Min(90, lat))
+		long = math.Mod(long+540, 360) - 180
+
+		m.SetFloat(prefix+"_lat", units.Semicircles(lat))
+		m.SetFloat(prefix+"_long", units.Semicircles(long))
+	}
+
+	return removed
+}
+
+// ApplyPrivacy decodes the FIT file read from r, obscures its positions with
+// p and writes it to w. Positions are the only change made to the file.
+func ApplyPrivacy(w io.Writer, r io.Reader, p Privacy) error {
+	d := NewDecoder(r)
+	e := NewEncoder(w)
+	for {
+		m, err := d.Next()
+		if err == io.EOF {
+			return e.Close()
+		}
+		if err != nil {
+			return err
+		}
+
+		p.Apply(m)
+		if err := e.Encode(m); err != nil {
+			return err
+		}
+	}
+}
+
+// distance returns the great circle distance in meters between two
+// positions in degrees.
+func distance(lat1, long1, lat2, long2 float64) float64 {
+	rad := math.Pi / 180
+	dlat := (lat2 - lat1) * rad
+	dlong := (long2 - long1) * rad
+
+	h := math.Sin(dlat/2)*math.Sin(dlat/2) + math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dlong/2)*math.Sin(dlong/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(math.Min(1, h)))
+}
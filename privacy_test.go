@@ -0,0 +1,92 @@
+package gofit
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"testing"
+)
+
+func TestApplyPrivacy(t *testing.T) {
+	data, err := os.ReadFile("testfiles/test2.fit")
+	if err != nil {
+		t.Fatalf("%s\n", err)
+	}
+	a, _ := ReadActivity(bytes.NewReader(data))
+
+	home := PrivacyZone{Radius: 500}
+	home.Lat, home.Long, _ = a.Records[0].Position("position")
+
+	var buf bytes.Buffer
+	if err := ApplyPrivacy(&buf, bytes.NewReader(data), Privacy{Zones: []PrivacyZone{home}}); err != nil {
+		t.Fatalf("%s\n", err)
+	}
+	if crc := CRC(buf.Bytes()); crc != 0 {
+		t.Errorf("got file CRC %04x", crc)
+	}
+	b, err := ReadActivity(&buf)
+	if err != nil {
+		t.Fatalf("%s\n", err)
+	}
+
+	if len(b.Records) != len(a.Records) {
+		t.Fatalf("got %d records", len(b.Records))
+	}
+
+	removed := 0
+	for i, r := range a.Records {
+		lat, long, ok := r.Position("position")
+		gotLat, gotLong, gotOK := b.Records[i].Position("position")
+
+		switch {
+		case ok && home.Contains(lat, long):
+			if gotOK {
+				t.Fatalf("record %d: position within the zone kept", i)
+			}
+			removed++
+		case gotOK != ok || gotLat != lat || gotLong != long:
+			t.Fatalf("record %d: position outside the zone changed", i)
+		}
+
+		for _, name := range []string{"heart_rate", "power", "distance", "altitude"} {
+			want, wok := r.Float(name)
+			if got, ok := b.Records[i].Float(name); ok != wok || got != want {
+				t.Fatalf("record %d: %s changed", i, name)
+			}
+		}
+	}
+	if removed == 0 || removed == len(a.Records) {
+		t.Errorf("removed %d of %d positions", removed, len(a.Records))
+	}
+
+	if _, _, ok := b.Sessions[0].Position("start_position"); ok {
+		t.Errorf("session start position kept")
+	}
+}
+
+func TestPrivacyShiftAndGrid(t *testing.T) {
+	a := readActivity(t, "testfiles/test2.fit")
+	r := a.Records[100]
+	lat, long, _ := r.Position("position")
+
+	Privacy{ShiftLat: 0.5, ShiftLong: -0.25, Grid: 0.01}.Apply(r)
+
+	gotLat, gotLong, ok := r.Position("position")
+	if !ok {
+		t.Fatalf("position removed")
+	}
+	wantLat := math.Round((lat+0.5)/0.01) * 0.01
+	wantLong := math.Round((long-0.25)/0.01) * 0.01
+	if math.Abs(gotLat-wantLat) > 1e-6 || math.Abs(gotLong-wantLong) > 1e-6 {
+		t.Errorf("got %f, %f, want %f, %f", gotLat, gotLong, wantLat, wantLong)
+	}
+}
+
+func TestPrivacyZoneContains(t *testing.T) {
+	z := PrivacyZone{Lat: 51.5, Long: -0.1, Radius: 1000}
+
+	// A thousandth of a degree of latitude is about 111 m
+	if !z.Contains(51.505, -0.1) || z.Contains(51.51, -0.1) {
+		t.Errorf("wrong zone boundary")
+	}
+}
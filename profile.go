@@ -0,0 +1,666 @@
+package gofit
+
+// Base type numbers, as found in FieldDefinition.Type
+const (
+	TypeEnum byte = iota
+	TypeSint8
+	TypeUint8
+	TypeSint16
+	TypeUint16
+	TypeSint32
+	TypeUint32
+	TypeString
+	TypeFloat32
+	TypeFloat64
+	TypeUint8z
+	TypeUint16z
+	TypeUint32z
+	TypeByte
+	TypeSint64
+	TypeUint64
+	TypeUint64z
+)
+
+// Size in bytes of a single value of each base type
+var baseTypeSizes = [...]int{1, 1, 1, 2, 2, 4, 4, 1, 4, 8, 1, 2, 4, 1, 8, 8, 8}
+
+// Invalid value of each base type, widened to 64 bits
+var baseTypeInvalid = [...]uint64{
+	0xFF, 0x7F, 0xFF, 0x7FFF, 0xFFFF, 0x7FFFFFFF, 0xFFFFFFFF, 0x00, 0xFFFFFFFF,
+	0xFFFFFFFFFFFFFFFF, 0x00, 0x0000, 0x00000000, 0xFF, 0x7FFFFFFFFFFFFFFF,
+	0xFFFFFFFFFFFFFFFF, 0x0000000000000000,
+}
+
+// baseTypeSize returns the size of one value of a base type, treating
+// unknown types as single bytes.
+func baseTypeSize(baseType byte) int {
+	if int(baseType) < len(baseTypeSizes) {
+		return baseTypeSizes[baseType]
+	}
+	return 1
+}
+
+// Global message numbers
+const (
+	MesgFileID           uint16 = 0
+	MesgUserProfile      uint16 = 3
+	MesgZonesTarget      uint16 = 7
+	MesgHrZone           uint16 = 8
+	MesgPowerZone        uint16 = 9
+	MesgSport            uint16 = 12
+	MesgSession          uint16 = 18
+	MesgLap              uint16 = 19
+	MesgRecord           uint16 = 20
+	MesgEvent            uint16 = 21
+	MesgDeviceInfo       uint16 = 23
+	MesgWorkout          uint16 = 26
+	MesgActivity         uint16 = 34
+	MesgSoftware         uint16 = 35
+	MesgFileCreator      uint16 = 49
+	MesgSpeedZone        uint16 = 53
+	MesgMonitoring       uint16 = 55
+	MesgHrv              uint16 = 78
+	MesgLength           uint16 = 101
+	MesgMonitoringInfo   uint16 = 103
+	MesgCadenceZone      uint16 = 131
+	MesgHr               uint16 = 132
+	MesgFieldDescription uint16 = 206
+	MesgDeveloperDataID  uint16 = 207
+	MesgTimeInZone       uint16 = 216
+)
+
+// MessageProfile describes a message in the FIT profile.
+type MessageProfile struct {
+	Name   string
+	Fields map[byte]*FieldProfile
+}
+
+// FieldProfile describes a field of a message in the FIT profile. Values are
+// decoded as raw / Scale - Offset.
+type FieldProfile struct {
+	Name   string
+	Type   byte
+	Array  bool
+	Scale  float64
+	Offset float64
+	Units  string
+
+	// Values packed into this field which expand into other fields
+	Components []Component
+
+	// Alternative interpretations of the field selected by the value of
+	// another field
+	Subfields []Subfield
+}
+
+// Subfield is an alternative interpretation of a field, used in place of the
+// field when any of its references matches the message.
+type Subfield struct {
+	FieldProfile
+	Refs []SubfieldRef
+}
+
+// SubfieldRef selects a subfield when field Field holds Value.
+type SubfieldRef struct {
+	Field byte
+	Value float64
+}
+
+// Component is a run of bits within a field that expands into another field
+// of the same message. Accumulated components are deltas that are summed
+// across messages into the destination field.
+type Component struct {
+	Field      byte
+	Bits       uint
+	Scale      float64
+	Offset     float64
+	Accumulate bool
+}
+
+// LookupProfile returns the profile of message mesgNum, or nil if it is not
+// part of the profile gofit knows about.
+func LookupProfile(mesgNum uint16) *MessageProfile {
+	return profile[mesgNum]
+}
+
+// Field returns the profile of field num, falling back to the fields common
+// to every message. It returns nil for unknown fields.
+func (p *MessageProfile) Field(num byte) *FieldProfile {
+	if p != nil {
+		if fp := p.Fields[num]; fp != nil {
+			return fp
+		}
+	}
+	return commonFields[num]
+}
+
+// Resolve returns the profile of the subfield selected by the other fields
+// of m, or f itself if no subfield applies.
+func (f *FieldProfile) Resolve(m *Message) *FieldProfile {
+	for i := range f.Subfields {
+		for _, ref := range f.Subfields[i].Refs {
+			if field := m.FieldNum(ref.Field); field != nil && field.Value == ref.Value {
+				return &f.Subfields[i].FieldProfile
+			}
+		}
+	}
+	return f
+}
+
+func (f *FieldProfile) scale() float64 {
+	if f.Scale == 0 {
+		return 1
+	}
+	return f.Scale
+}
+
+func (c *Component) scale() float64 {
+	if c.Scale == 0 {
+		return 1
+	}
+	return c.Scale
+}
+
+// Products are numbered per manufacturer
+var productSubfields = []Subfield{
+	{FieldProfile{Name: "favero_product", Type: TypeUint16}, []SubfieldRef{{1, 263}}},
+	{FieldProfile{Name: "garmin_product", Type: TypeUint16}, []SubfieldRef{{1, 1}, {1, 15}, {1, 13}, {1, 89}}},
+}
+
+// Cycles are strides or strokes depending on the sport
+var totalCyclesSubfields = []Subfield{
+	{FieldProfile{Name: "total_strides", Type: TypeUint32, Units: "strides"}, []SubfieldRef{{5, 1}, {5, 11}}},
+	{FieldProfile{Name: "total_strokes", Type: TypeUint32, Units: "strokes"}, []SubfieldRef{{5, 2}, {5, 5}, {5, 15}, {5, 37}}},
+}
+
+var commonFields = map[byte]*FieldProfile{
+	250: {Name: "part_index", Type: TypeUint32},
+	253: {Name: "timestamp", Type: TypeUint32, Units: "s"},
+	254: {Name: "message_index", Type: TypeUint16},
+}
+
+var profile = map[uint16]*MessageProfile{
+	MesgFileID: {Name: "file_id", Fields: map[byte]*FieldProfile{
+		0: {Name: "type", Type: TypeEnum},
+		1: {Name: "manufacturer", Type: TypeUint16},
+		2: {Name: "product", Type: TypeUint16, Subfields: productSubfields},
+		3: {Name: "serial_number", Type: TypeUint32z},
+		4: {Name: "time_created", Type: TypeUint32, Units: "s"},
+		5: {Name: "number", Type: TypeUint16},
+		8: {Name: "product_name", Type: TypeString},
+	}},
+	MesgUserProfile: {Name: "user_profile", Fields: map[byte]*FieldProfile{
+		0:  {Name: "friendly_name", Type: TypeString},
+		1:  {Name: "gender", Type: TypeEnum},
+		2:  {Name: "age", Type: TypeUint8, Units: "years"},
+		3:  {Name: "height", Type: TypeUint8, Scale: 100, Units: "m"},
+		4:  {Name: "weight", Type: TypeUint16, Scale: 10, Units: "kg"},
+		5:  {Name: "language", Type: TypeEnum},
+		6:  {Name: "elev_setting", Type: TypeEnum},
+		7:  {Name: "weight_setting", Type: TypeEnum},
+		8:  {Name: "resting_heart_rate", Type: TypeUint8, Units: "bpm"},
+		9:  {Name: "default_max_running_heart_rate", Type: TypeUint8, Units: "bpm"},
+		10: {Name: "default_max_biking_heart_rate", Type: TypeUint8, Units: "bpm"},
+		11: {Name: "default_max_heart_rate", Type: TypeUint8, Units: "bpm"},
+		12: {Name: "hr_setting", Type: TypeEnum},
+		13: {Name: "speed_setting", Type: TypeEnum},
+		14: {Name: "dist_setting", Type: TypeEnum},
+		16: {Name: "power_setting", Type: TypeEnum},
+		17: {Name: "activity_class", Type: TypeEnum},
+		18: {Name: "position_setting", Type: TypeEnum},
+		21: {Name: "temperature_setting", Type: TypeEnum},
+		22: {Name: "local_id", Type: TypeUint16},
+		23: {Name: "global_id", Type: TypeByte, Array: true},
+		28: {Name: "wake_time", Type: TypeUint32},
+		29: {Name: "sleep_time", Type: TypeUint32},
+		30: {Name: "height_setting", Type: TypeEnum},
+		31: {Name: "user_running_step_length", Type: TypeUint16, Scale: 1000, Units: "m"},
+		32: {Name: "user_walking_step_length", Type: TypeUint16, Scale: 1000, Units: "m"},
+	}},
+	MesgZonesTarget: {Name: "zones_target", Fields: map[byte]*FieldProfile{
+		1: {Name: "max_heart_rate", Type: TypeUint8},
+		2: {Name: "threshold_heart_rate", Type: TypeUint8},
+		3: {Name: "functional_threshold_power", Type: TypeUint16},
+		5: {Name: "hr_calc_type", Type: TypeEnum},
+		7: {Name: "pwr_calc_type", Type: TypeEnum},
+	}},
+	MesgHrZone: {Name: "hr_zone", Fields: map[byte]*FieldProfile{
+		1: {Name: "high_bpm", Type: TypeUint8, Units: "bpm"},
+		2: {Name: "name", Type: TypeString},
+	}},
+	MesgPowerZone: {Name: "power_zone", Fields: map[byte]*FieldProfile{
+		1: {Name: "high_value", Type: TypeUint16, Units: "watts"},
+		2: {Name: "name", Type: TypeString},
+	}},
+	MesgSpeedZone: {Name: "speed_zone", Fields: map[byte]*FieldProfile{
+		0: {Name: "high_value", Type: TypeUint16, Scale: 1000, Units: "m/s"},
+		1: {Name: "name", Type: TypeString},
+	}},
+	MesgCadenceZone: {Name: "cadence_zone", Fields: map[byte]*FieldProfile{
+		0: {Name: "high_value", Type: TypeUint8, Units: "rpm"},
+		1: {Name: "name", Type: TypeString},
+	}},
+	MesgSport: {Name: "sport", Fields: map[byte]*FieldProfile{
+		0: {Name: "sport", Type: TypeEnum},
+		1: {Name: "sub_sport", Type: TypeEnum},
+		3: {Name: "name", Type: TypeString},
+	}},
+	MesgSession: {Name: "session", Fields: map[byte]*FieldProfile{
+		0:   {Name: "event", Type: TypeEnum},
+		1:   {Name: "event_type", Type: TypeEnum},
+		2:   {Name: "start_time", Type: TypeUint32, Units: "s"},
+		3:   {Name: "start_position_lat", Type: TypeSint32, Units: "semicircles"},
+		4:   {Name: "start_position_long", Type: TypeSint32, Units: "semicircles"},
+		5:   {Name: "sport", Type: TypeEnum},
+		6:   {Name: "sub_sport", Type: TypeEnum},
+		7:   {Name: "total_elapsed_time", Type: TypeUint32, Scale: 1000, Units: "s"},
+		8:   {Name: "total_timer_time", Type: TypeUint32, Scale: 1000, Units: "s"},
+		9:   {Name: "total_distance", Type: TypeUint32, Scale: 100, Units: "m"},
+		10:  {Name: "total_cycles", Type: TypeUint32, Units: "cycles", Subfields: totalCyclesSubfields},
+		11:  {Name: "total_calories", Type: TypeUint16, Units: "kcal"},
+		13:  {Name: "total_fat_calories", Type: TypeUint16, Units: "kcal"},
+		14:  {Name: "avg_speed", Type: TypeUint16, Scale: 1000, Units: "m/s", Components: []Component{{Field: 124, Bits: 16, Scale: 1000}}},
+		15:  {Name: "max_speed", Type: TypeUint16, Scale: 1000, Units: "m/s", Components: []Component{{Field: 125, Bits: 16, Scale: 1000}}},
+		16:  {Name: "avg_heart_rate", Type: TypeUint8, Units: "bpm"},
+		17:  {Name: "max_heart_rate", Type: TypeUint8, Units: "bpm"},
+		18:  {Name: "avg_cadence", Type: TypeUint8, Units: "rpm", Subfields: []Subfield{{FieldProfile{Name: "avg_running_cadence", Type: TypeUint8, Units: "strides/min"}, []SubfieldRef{{5, 1}}}}},
+		19:  {Name: "max_cadence", Type: TypeUint8, Units: "rpm", Subfields: []Subfield{{FieldProfile{Name: "max_running_cadence", Type: TypeUint8, Units: "strides/min"}, []SubfieldRef{{5, 1}}}}},
+		20:  {Name: "avg_power", Type: TypeUint16, Units: "watts"},
+		21:  {Name: "max_power", Type: TypeUint16, Units: "watts"},
+		22:  {Name: "total_ascent", Type: TypeUint16, Units: "m"},
+		23:  {Name: "total_descent", Type: TypeUint16, Units: "m"},
+		24:  {Name: "total_training_effect", Type: TypeUint8, Scale: 10},
+		25:  {Name: "first_lap_index", Type: TypeUint16},
+		26:  {Name: "num_laps", Type: TypeUint16},
+		27:  {Name: "event_group", Type: TypeUint8},
+		28:  {Name: "trigger", Type: TypeEnum},
+		29:  {Name: "nec_lat", Type: TypeSint32, Units: "semicircles"},
+		30:  {Name: "nec_long", Type: TypeSint32, Units: "semicircles"},
+		31:  {Name: "swc_lat", Type: TypeSint32, Units: "semicircles"},
+		32:  {Name: "swc_long", Type: TypeSint32, Units: "semicircles"},
+		33:  {Name: "num_lengths", Type: TypeUint16, Units: "lengths"},
+		34:  {Name: "normalized_power", Type: TypeUint16, Units: "watts"},
+		35:  {Name: "training_stress_score", Type: TypeUint16, Scale: 10, Units: "tss"},
+		36:  {Name: "intensity_factor", Type: TypeUint16, Scale: 1000, Units: "if"},
+		37:  {Name: "left_right_balance", Type: TypeUint16},
+		38:  {Name: "end_position_lat", Type: TypeSint32, Units: "semicircles"},
+		39:  {Name: "end_position_long", Type: TypeSint32, Units: "semicircles"},
+		41:  {Name: "avg_stroke_count", Type: TypeUint32, Scale: 10, Units: "strokes/lap"},
+		42:  {Name: "avg_stroke_distance", Type: TypeUint16, Scale: 100, Units: "m"},
+		43:  {Name: "swim_stroke", Type: TypeEnum},
+		44:  {Name: "pool_length", Type: TypeUint16, Scale: 100, Units: "m"},
+		45:  {Name: "threshold_power", Type: TypeUint16, Units: "watts"},
+		46:  {Name: "pool_length_unit", Type: TypeEnum},
+		47:  {Name: "num_active_lengths", Type: TypeUint16, Units: "lengths"},
+		48:  {Name: "total_work", Type: TypeUint32, Units: "J"},
+		49:  {Name: "avg_altitude", Type: TypeUint16, Scale: 5, Offset: 500, Units: "m", Components: []Component{{Field: 126, Bits: 16, Scale: 5, Offset: 500}}},
+		50:  {Name: "max_altitude", Type: TypeUint16, Scale: 5, Offset: 500, Units: "m", Components: []Component{{Field: 128, Bits: 16, Scale: 5, Offset: 500}}},
+		51:  {Name: "gps_accuracy", Type: TypeUint8, Units: "m"},
+		52:  {Name: "avg_grade", Type: TypeSint16, Scale: 100, Units: "%"},
+		57:  {Name: "avg_temperature", Type: TypeSint8, Units: "C"},
+		58:  {Name: "max_temperature", Type: TypeSint8, Units: "C"},
+		59:  {Name: "total_moving_time", Type: TypeUint32, Scale: 1000, Units: "s"},
+		64:  {Name: "min_heart_rate", Type: TypeUint8, Units: "bpm"},
+		65:  {Name: "time_in_hr_zone", Type: TypeUint32, Array: true, Scale: 1000, Units: "s"},
+		66:  {Name: "time_in_speed_zone", Type: TypeUint32, Array: true, Scale: 1000, Units: "s"},
+		67:  {Name: "time_in_cadence_zone", Type: TypeUint32, Array: true, Scale: 1000, Units: "s"},
+		68:  {Name: "time_in_power_zone", Type: TypeUint32, Array: true, Scale: 1000, Units: "s"},
+		69:  {Name: "avg_lap_time", Type: TypeUint32, Scale: 1000, Units: "s"},
+		70:  {Name: "best_lap_index", Type: TypeUint16},
+		71:  {Name: "min_altitude", Type: TypeUint16, Scale: 5, Offset: 500, Units: "m", Components: []Component{{Field: 127, Bits: 16, Scale: 5, Offset: 500}}},
+		89:  {Name: "avg_vertical_oscillation", Type: TypeUint16, Scale: 10, Units: "mm"},
+		90:  {Name: "avg_stance_time_percent", Type: TypeUint16, Scale: 100, Units: "percent"},
+		91:  {Name: "avg_stance_time", Type: TypeUint16, Scale: 10, Units: "ms"},
+		92:  {Name: "avg_fractional_cadence", Type: TypeUint8, Scale: 128, Units: "rpm"},
+		93:  {Name: "max_fractional_cadence", Type: TypeUint8, Scale: 128, Units: "rpm"},
+		94:  {Name: "total_fractional_cycles", Type: TypeUint8, Scale: 128, Units: "cycles"},
+		110: {Name: "sport_profile_name", Type: TypeString},
+		111: {Name: "sport_index", Type: TypeUint8},
+		112: {Name: "time_standing", Type: TypeUint32, Scale: 1000, Units: "s"},
+		113: {Name: "stand_count", Type: TypeUint16},
+		124: {Name: "enhanced_avg_speed", Type: TypeUint32, Scale: 1000, Units: "m/s"},
+		125: {Name: "enhanced_max_speed", Type: TypeUint32, Scale: 1000, Units: "m/s"},
+		126: {Name: "enhanced_avg_altitude", Type: TypeUint32, Scale: 5, Offset: 500, Units: "m"},
+		127: {Name: "enhanced_min_altitude", Type: TypeUint32, Scale: 5, Offset: 500, Units: "m"},
+		128: {Name: "enhanced_max_altitude", Type: TypeUint32, Scale: 5, Offset: 500, Units: "m"},
+		132: {Name: "avg_vertical_ratio", Type: TypeUint16, Scale: 100, Units: "percent"},
+		133: {Name: "avg_stance_time_balance", Type: TypeUint16, Scale: 100, Units: "percent"},
+		134: {Name: "avg_step_length", Type: TypeUint16, Scale: 10, Units: "mm"},
+	}},
+	MesgLap: {Name: "lap", Fields: map[byte]*FieldProfile{
+		0: {Name: "event", Type: TypeEnum},
+		1: {Name: "event_type", Type: TypeEnum},
+		2: {Name: "start_time", Type: TypeUint32, Units: "s"},
+		3: {Name: "start_position_lat", Type: TypeSint32, Units: "semicircles"},
+		4: {Name: "start_position_long", Type: TypeSint32, Units: "semicircles"},
+		5: {Name: "end_position_lat", Type: TypeSint32, Units: "semicircles"},
+		6: {Name: "end_position_long", Type: TypeSint32, Units: "semicircles"},
+		7: {Name: "total_elapsed_time", Type: TypeUint32, Scale: 1000, Units: "s"},
+		8: {Name: "total_timer_time", Type: TypeUint32, Scale: 1000, Units: "s"},
+		9: {Name: "total_distance", Type: TypeUint32, Scale: 100, Units: "m"},
+		10: {Name: "total_cycles", Type: TypeUint32, Units: "cycles", Subfields: []Subfield{
+			{FieldProfile{Name: "total_strides", Type: TypeUint32, Units: "strides"}, []SubfieldRef{{25, 1}, {25, 11}}},
+			{FieldProfile{Name: "total_strokes", Type: TypeUint32, Units: "strokes"}, []SubfieldRef{{25, 2}, {25, 5}, {25, 15}, {25, 37}}},
+		}},
+		11:  {Name: "total_calories", Type: TypeUint16, Units: "kcal"},
+		12:  {Name: "total_fat_calories", Type: TypeUint16, Units: "kcal"},
+		13:  {Name: "avg_speed", Type: TypeUint16, Scale: 1000, Units: "m/s", Components: []Component{{Field: 110, Bits: 16, Scale: 1000}}},
+		14:  {Name: "max_speed", Type: TypeUint16, Scale: 1000, Units: "m/s", Components: []Component{{Field: 111, Bits: 16, Scale: 1000}}},
+		15:  {Name: "avg_heart_rate", Type: TypeUint8, Units: "bpm"},
+		16:  {Name: "max_heart_rate", Type: TypeUint8, Units: "bpm"},
+		17:  {Name: "avg_cadence", Type: TypeUint8, Units: "rpm", Subfields: []Subfield{{FieldProfile{Name: "avg_running_cadence", Type: TypeUint8, Units: "strides/min"}, []SubfieldRef{{25, 1}}}}},
+		18:  {Name: "max_cadence", Type: TypeUint8, Units: "rpm", Subfields: []Subfield{{FieldProfile{Name: "max_running_cadence", Type: TypeUint8, Units: "strides/min"}, []SubfieldRef{{25, 1}}}}},
+		19:  {Name: "avg_power", Type: TypeUint16, Units: "watts"},
+		20:  {Name: "max_power", Type: TypeUint16, Units: "watts"},
+		21:  {Name: "total_ascent", Type: TypeUint16, Units: "m"},
+		22:  {Name: "total_descent", Type: TypeUint16, Units: "m"},
+		23:  {Name: "intensity", Type: TypeEnum},
+		24:  {Name: "lap_trigger", Type: TypeEnum},
+		25:  {Name: "sport", Type: TypeEnum},
+		26:  {Name: "event_group", Type: TypeUint8},
+		32:  {Name: "num_lengths", Type: TypeUint16, Units: "lengths"},
+		33:  {Name: "normalized_power", Type: TypeUint16, Units: "watts"},
+		34:  {Name: "left_right_balance", Type: TypeUint16},
+		35:  {Name: "first_length_index", Type: TypeUint16},
+		37:  {Name: "avg_stroke_distance", Type: TypeUint16, Scale: 100, Units: "m"},
+		38:  {Name: "swim_stroke", Type: TypeEnum},
+		39:  {Name: "sub_sport", Type: TypeEnum},
+		40:  {Name: "num_active_lengths", Type: TypeUint16, Units: "lengths"},
+		41:  {Name: "total_work", Type: TypeUint32, Units: "J"},
+		42:  {Name: "avg_altitude", Type: TypeUint16, Scale: 5, Offset: 500, Units: "m", Components: []Component{{Field: 112, Bits: 16, Scale: 5, Offset: 500}}},
+		43:  {Name: "max_altitude", Type: TypeUint16, Scale: 5, Offset: 500, Units: "m", Components: []Component{{Field: 114, Bits: 16, Scale: 5, Offset: 500}}},
+		44:  {Name: "gps_accuracy", Type: TypeUint8, Units: "m"},
+		45:  {Name: "avg_grade", Type: TypeSint16, Scale: 100, Units: "%"},
+		57:  {Name: "time_in_hr_zone", Type: TypeUint32, Array: true, Scale: 1000, Units: "s"},
+		58:  {Name: "time_in_speed_zone", Type: TypeUint32, Array: true, Scale: 1000, Units: "s"},
+		59:  {Name: "time_in_cadence_zone", Type: TypeUint32, Array: true, Scale: 1000, Units: "s"},
+		60:  {Name: "time_in_power_zone", Type: TypeUint32, Array: true, Scale: 1000, Units: "s"},
+		61:  {Name: "repetition_num", Type: TypeUint16},
+		62:  {Name: "min_altitude", Type: TypeUint16, Scale: 5, Offset: 500, Units: "m", Components: []Component{{Field: 113, Bits: 16, Scale: 5, Offset: 500}}},
+		63:  {Name: "min_heart_rate", Type: TypeUint8, Units: "bpm"},
+		71:  {Name: "wkt_step_index", Type: TypeUint16},
+		74:  {Name: "opponent_score", Type: TypeUint16},
+		75:  {Name: "stroke_count", Type: TypeUint16, Array: true, Units: "counts"},
+		76:  {Name: "zone_count", Type: TypeUint16, Array: true, Units: "counts"},
+		77:  {Name: "avg_vertical_oscillation", Type: TypeUint16, Scale: 10, Units: "mm"},
+		78:  {Name: "avg_stance_time_percent", Type: TypeUint16, Scale: 100, Units: "percent"},
+		79:  {Name: "avg_stance_time", Type: TypeUint16, Scale: 10, Units: "ms"},
+		80:  {Name: "avg_fractional_cadence", Type: TypeUint8, Scale: 128, Units: "rpm"},
+		81:  {Name: "max_fractional_cadence", Type: TypeUint8, Scale: 128, Units: "rpm"},
+		82:  {Name: "total_fractional_cycles", Type: TypeUint8, Scale: 128, Units: "cycles"},
+		110: {Name: "enhanced_avg_speed", Type: TypeUint32, Scale: 1000, Units: "m/s"},
+		111: {Name: "enhanced_max_speed", Type: TypeUint32, Scale: 1000, Units: "m/s"},
+		112: {Name: "enhanced_avg_altitude", Type: TypeUint32, Scale: 5, Offset: 500, Units: "m"},
+		113: {Name: "enhanced_min_altitude", Type: TypeUint32, Scale: 5, Offset: 500, Units: "m"},
+		114: {Name: "enhanced_max_altitude", Type: TypeUint32, Scale: 5, Offset: 500, Units: "m"},
+		118: {Name: "avg_vertical_ratio", Type: TypeUint16, Scale: 100, Units: "percent"},
+		119: {Name: "avg_stance_time_balance", Type: TypeUint16, Scale: 100, Units: "percent"},
+		120: {Name: "avg_step_length", Type: TypeUint16, Scale: 10, Units: "mm"},
+	}},
+	MesgRecord: {Name: "record", Fields: map[byte]*FieldProfile{
+		0:   {Name: "position_lat", Type: TypeSint32, Units: "semicircles"},
+		1:   {Name: "position_long", Type: TypeSint32, Units: "semicircles"},
+		2:   {Name: "altitude", Type: TypeUint16, Scale: 5, Offset: 500, Units: "m", Components: []Component{{Field: 78, Bits: 16, Scale: 5, Offset: 500}}},
+		3:   {Name: "heart_rate", Type: TypeUint8, Units: "bpm"},
+		4:   {Name: "cadence", Type: TypeUint8, Units: "rpm"},
+		5:   {Name: "distance", Type: TypeUint32, Scale: 100, Units: "m"},
+		6:   {Name: "speed", Type: TypeUint16, Scale: 1000, Units: "m/s", Components: []Component{{Field: 73, Bits: 16, Scale: 1000}}},
+		7:   {Name: "power", Type: TypeUint16, Units: "watts"},
+		8:   {Name: "compressed_speed_distance", Type: TypeByte, Array: true, Components: []Component{{Field: 6, Bits: 12, Scale: 100}, {Field: 5, Bits: 12, Scale: 16, Accumulate: true}}},
+		9:   {Name: "grade", Type: TypeSint16, Scale: 100, Units: "%"},
+		10:  {Name: "resistance", Type: TypeUint8},
+		11:  {Name: "time_from_course", Type: TypeSint32, Scale: 1000, Units: "s"},
+		12:  {Name: "cycle_length", Type: TypeUint8, Scale: 100, Units: "m"},
+		13:  {Name: "temperature", Type: TypeSint8, Units: "C"},
+		17:  {Name: "speed_1s", Type: TypeUint8, Array: true, Scale: 16, Units: "m/s"},
+		18:  {Name: "cycles", Type: TypeUint8, Units: "cycles", Components: []Component{{Field: 19, Bits: 8, Accumulate: true}}},
+		19:  {Name: "total_cycles", Type: TypeUint32, Units: "cycles"},
+		28:  {Name: "compressed_accumulated_power", Type: TypeUint16, Units: "watts", Components: []Component{{Field: 29, Bits: 16, Accumulate: true}}},
+		29:  {Name: "accumulated_power", Type: TypeUint32, Units: "watts"},
+		30:  {Name: "left_right_balance", Type: TypeUint8},
+		31:  {Name: "gps_accuracy", Type: TypeUint8, Units: "m"},
+		32:  {Name: "vertical_speed", Type: TypeSint16, Scale: 1000, Units: "m/s"},
+		33:  {Name: "calories", Type: TypeUint16, Units: "kcal"},
+		39:  {Name: "vertical_oscillation", Type: TypeUint16, Scale: 10, Units: "mm"},
+		40:  {Name: "stance_time_percent", Type: TypeUint16, Scale: 100, Units: "percent"},
+		41:  {Name: "stance_time", Type: TypeUint16, Scale: 10, Units: "ms"},
+		42:  {Name: "activity_type", Type: TypeEnum},
+		43:  {Name: "left_torque_effectiveness", Type: TypeUint8, Scale: 2, Units: "percent"},
+		44:  {Name: "right_torque_effectiveness", Type: TypeUint8, Scale: 2, Units: "percent"},
+		45:  {Name: "left_pedal_smoothness", Type: TypeUint8, Scale: 2, Units: "percent"},
+		46:  {Name: "right_pedal_smoothness", Type: TypeUint8, Scale: 2, Units: "percent"},
+		47:  {Name: "combined_pedal_smoothness", Type: TypeUint8, Scale: 2, Units: "percent"},
+		48:  {Name: "time128", Type: TypeUint8, Scale: 128, Units: "s"},
+		49:  {Name: "stroke_type", Type: TypeEnum},
+		50:  {Name: "zone", Type: TypeUint8},
+		51:  {Name: "ball_speed", Type: TypeUint16, Scale: 100, Units: "m/s"},
+		52:  {Name: "cadence256", Type: TypeUint16, Scale: 256, Units: "rpm"},
+		53:  {Name: "fractional_cadence", Type: TypeUint8, Scale: 128, Units: "rpm"},
+		62:  {Name: "device_index", Type: TypeUint8},
+		73:  {Name: "enhanced_speed", Type: TypeUint32, Scale: 1000, Units: "m/s"},
+		78:  {Name: "enhanced_altitude", Type: TypeUint32, Scale: 5, Offset: 500, Units: "m"},
+		81:  {Name: "battery_soc", Type: TypeUint8, Scale: 2, Units: "percent"},
+		82:  {Name: "motor_power", Type: TypeUint16, Units: "watts"},
+		83:  {Name: "vertical_ratio", Type: TypeUint16, Scale: 100, Units: "percent"},
+		84:  {Name: "stance_time_balance", Type: TypeUint16, Scale: 100, Units: "percent"},
+		85:  {Name: "step_length", Type: TypeUint16, Scale: 10, Units: "mm"},
+		87:  {Name: "cycle_length16", Type: TypeUint16, Scale: 100, Units: "m"},
+		91:  {Name: "absolute_pressure", Type: TypeUint32, Units: "Pa"},
+		92:  {Name: "depth", Type: TypeUint32, Scale: 1000, Units: "m"},
+		108: {Name: "enhanced_respiration_rate", Type: TypeUint16, Scale: 100, Units: "Breaths/min"},
+	}},
+	MesgEvent: {Name: "event", Fields: map[byte]*FieldProfile{
+		0: {Name: "event", Type: TypeEnum},
+		1: {Name: "event_type", Type: TypeEnum},
+		2: {Name: "data16", Type: TypeUint16, Components: []Component{{Field: 3, Bits: 16}}},
+		3: {Name: "data", Type: TypeUint32, Subfields: []Subfield{
+			{FieldProfile{Name: "timer_trigger", Type: TypeEnum}, []SubfieldRef{{0, 0}}},
+			{FieldProfile{Name: "course_point_index", Type: TypeUint16}, []SubfieldRef{{0, 10}}},
+			{FieldProfile{Name: "battery_level", Type: TypeUint16, Scale: 1000, Units: "V"}, []SubfieldRef{{0, 11}}},
+			{FieldProfile{Name: "virtual_partner_speed", Type: TypeUint16, Scale: 1000, Units: "m/s"}, []SubfieldRef{{0, 12}}},
+			{FieldProfile{Name: "hr_high_alert", Type: TypeUint8, Units: "bpm"}, []SubfieldRef{{0, 13}}},
+			{FieldProfile{Name: "hr_low_alert", Type: TypeUint8, Units: "bpm"}, []SubfieldRef{{0, 14}}},
+			{FieldProfile{Name: "speed_high_alert", Type: TypeUint32, Scale: 1000, Units: "m/s"}, []SubfieldRef{{0, 15}}},
+			{FieldProfile{Name: "speed_low_alert", Type: TypeUint32, Scale: 1000, Units: "m/s"}, []SubfieldRef{{0, 16}}},
+			{FieldProfile{Name: "cad_high_alert", Type: TypeUint16, Units: "rpm"}, []SubfieldRef{{0, 17}}},
+			{FieldProfile{Name: "cad_low_alert", Type: TypeUint16, Units: "rpm"}, []SubfieldRef{{0, 18}}},
+			{FieldProfile{Name: "power_high_alert", Type: TypeUint16, Units: "watts"}, []SubfieldRef{{0, 19}}},
+			{FieldProfile{Name: "power_low_alert", Type: TypeUint16, Units: "watts"}, []SubfieldRef{{0, 20}}},
+			{FieldProfile{Name: "time_duration_alert", Type: TypeUint32, Scale: 1000, Units: "s"}, []SubfieldRef{{0, 23}}},
+			{FieldProfile{Name: "distance_duration_alert", Type: TypeUint32, Scale: 100, Units: "m"}, []SubfieldRef{{0, 24}}},
+			{FieldProfile{Name: "calorie_duration_alert", Type: TypeUint32, Units: "calories"}, []SubfieldRef{{0, 25}}},
+			{FieldProfile{Name: "fitness_equipment_state", Type: TypeEnum}, []SubfieldRef{{0, 27}}},
+			{FieldProfile{Name: "sport_point", Type: TypeUint32, Components: []Component{{Field: 7, Bits: 16}, {Field: 8, Bits: 16}}}, []SubfieldRef{{0, 33}}},
+			{FieldProfile{Name: "gear_change_data", Type: TypeUint32, Components: []Component{{Field: 11, Bits: 8}, {Field: 12, Bits: 8}, {Field: 9, Bits: 8}, {Field: 10, Bits: 8}}}, []SubfieldRef{{0, 42}, {0, 43}}},
+			{FieldProfile{Name: "rider_position", Type: TypeEnum}, []SubfieldRef{{0, 44}}},
+			{FieldProfile{Name: "comm_timeout", Type: TypeUint16}, []SubfieldRef{{0, 47}}},
+			{FieldProfile{Name: "radar_threat_alert", Type: TypeUint32, Components: []Component{{Field: 21, Bits: 8}, {Field: 22, Bits: 8}, {Field: 23, Bits: 8, Scale: 10}, {Field: 24, Bits: 8, Scale: 10}}}, []SubfieldRef{{0, 75}}},
+		}},
+		4:  {Name: "event_group", Type: TypeUint8},
+		7:  {Name: "score", Type: TypeUint16},
+		8:  {Name: "opponent_score", Type: TypeUint16},
+		9:  {Name: "front_gear_num", Type: TypeUint8z},
+		10: {Name: "front_gear", Type: TypeUint8z},
+		11: {Name: "rear_gear_num", Type: TypeUint8z},
+		12: {Name: "rear_gear", Type: TypeUint8z},
+		13: {Name: "device_index", Type: TypeUint8},
+		21: {Name: "radar_threat_level_max", Type: TypeEnum},
+		22: {Name: "radar_threat_count", Type: TypeUint8},
+		23: {Name: "radar_threat_avg_approach_speed", Type: TypeUint8, Scale: 10, Units: "m/s"},
+		24: {Name: "radar_threat_max_approach_speed", Type: TypeUint8, Scale: 10, Units: "m/s"},
+	}},
+	MesgDeviceInfo: {Name: "device_info", Fields: map[byte]*FieldProfile{
+		0: {Name: "device_index", Type: TypeUint8},
+		1: {Name: "device_type", Type: TypeUint8, Subfields: []Subfield{
+			{FieldProfile{Name: "ble_device_type", Type: TypeEnum}, []SubfieldRef{{25, 3}}},
+			{FieldProfile{Name: "antplus_device_type", Type: TypeUint8}, []SubfieldRef{{25, 1}}},
+			{FieldProfile{Name: "ant_device_type", Type: TypeUint8}, []SubfieldRef{{25, 0}}},
+			{FieldProfile{Name: "local_device_type", Type: TypeUint8}, []SubfieldRef{{25, 5}}},
+		}},
+		2: {Name: "manufacturer", Type: TypeUint16},
+		3: {Name: "serial_number", Type: TypeUint32z},
+		4: {Name: "product", Type: TypeUint16, Subfields: []Subfield{
+			{FieldProfile{Name: "favero_product", Type: TypeUint16}, []SubfieldRef{{2, 263}}},
+			{FieldProfile{Name: "garmin_product", Type: TypeUint16}, []SubfieldRef{{2, 1}, {2, 15}, {2, 13}, {2, 89}}},
+		}},
+		5:  {Name: "software_version", Type: TypeUint16, Scale: 100},
+		6:  {Name: "hardware_version", Type: TypeUint8},
+		7:  {Name: "cum_operating_time", Type: TypeUint32, Units: "s"},
+		10: {Name: "battery_voltage", Type: TypeUint16, Scale: 256, Units: "V"},
+		11: {Name: "battery_status", Type: TypeUint8},
+		18: {Name: "sensor_position", Type: TypeEnum},
+		19: {Name: "descriptor", Type: TypeString},
+		20: {Name: "ant_transmission_type", Type: TypeUint8z},
+		21: {Name: "ant_device_number", Type: TypeUint16z},
+		22: {Name: "ant_network", Type: TypeEnum},
+		25: {Name: "source_type", Type: TypeEnum},
+		27: {Name: "product_name", Type: TypeString},
+		32: {Name: "battery_level", Type: TypeUint8, Units: "%"},
+	}},
+	MesgWorkout: {Name: "workout", Fields: map[byte]*FieldProfile{
+		4:  {Name: "sport", Type: TypeEnum},
+		5:  {Name: "capabilities", Type: TypeUint32z},
+		6:  {Name: "num_valid_steps", Type: TypeUint16},
+		8:  {Name: "wkt_name", Type: TypeString},
+		11: {Name: "sub_sport", Type: TypeEnum},
+		14: {Name: "pool_length", Type: TypeUint16, Scale: 100, Units: "m"},
+		15: {Name: "pool_length_unit", Type: TypeEnum},
+	}},
+	MesgActivity: {Name: "activity", Fields: map[byte]*FieldProfile{
+		0: {Name: "total_timer_time", Type: TypeUint32, Scale: 1000, Units: "s"},
+		1: {Name: "num_sessions", Type: TypeUint16},
+		2: {Name: "type", Type: TypeEnum},
+		3: {Name: "event", Type: TypeEnum},
+		4: {Name: "event_type", Type: TypeEnum},
+		5: {Name: "local_timestamp", Type: TypeUint32, Units: "s"},
+		6: {Name: "event_group", Type: TypeUint8},
+	}},
+	MesgSoftware: {Name: "software", Fields: map[byte]*FieldProfile{
+		3: {Name: "version", Type: TypeUint16, Scale: 100},
+		5: {Name: "part_number", Type: TypeString},
+	}},
+	MesgFileCreator: {Name: "file_creator", Fields: map[byte]*FieldProfile{
+		0: {Name: "software_version", Type: TypeUint16},
+		1: {Name: "hardware_version", Type: TypeUint8},
+	}},
+	MesgMonitoring: {Name: "monitoring", Fields: map[byte]*FieldProfile{
+		0: {Name: "device_index", Type: TypeUint8},
+		1: {Name: "calories", Type: TypeUint16, Units: "kcal"},
+		2: {Name: "distance", Type: TypeUint32, Scale: 100, Units: "m"},
+		3: {Name: "cycles", Type: TypeUint32, Scale: 2, Units: "cycles", Subfields: []Subfield{
+			{FieldProfile{Name: "steps", Type: TypeUint32, Units: "steps"}, []SubfieldRef{{5, 6}, {5, 1}}},
+			{FieldProfile{Name: "strokes", Type: TypeUint32, Scale: 2, Units: "strokes"}, []SubfieldRef{{5, 2}, {5, 5}}},
+		}},
+		4:  {Name: "active_time", Type: TypeUint32, Scale: 1000, Units: "s"},
+		5:  {Name: "activity_type", Type: TypeEnum},
+		6:  {Name: "activity_subtype", Type: TypeEnum},
+		7:  {Name: "activity_level", Type: TypeEnum},
+		8:  {Name: "distance_16", Type: TypeUint16, Units: "100 * m"},
+		9:  {Name: "cycles_16", Type: TypeUint16, Units: "2 * cycles (steps)"},
+		10: {Name: "active_time_16", Type: TypeUint16, Units: "s"},
+		11: {Name: "local_timestamp", Type: TypeUint32, Units: "s"},
+		12: {Name: "temperature", Type: TypeSint16, Scale: 100, Units: "C"},
+		14: {Name: "temperature_min", Type: TypeSint16, Scale: 100, Units: "C"},
+		15: {Name: "temperature_max", Type: TypeSint16, Scale: 100, Units: "C"},
+		16: {Name: "activity_time", Type: TypeUint16, Array: true, Units: "minutes"},
+		19: {Name: "active_calories", Type: TypeUint16, Units: "kcal"},
+		24: {Name: "current_activity_type_intensity", Type: TypeByte, Components: []Component{{Field: 5, Bits: 5}, {Field: 28, Bits: 3}}},
+		25: {Name: "timestamp_min_8", Type: TypeUint8, Units: "min"},
+		26: {Name: "timestamp_16", Type: TypeUint16, Units: "s"},
+		27: {Name: "heart_rate", Type: TypeUint8, Units: "bpm"},
+		28: {Name: "intensity", Type: TypeUint8, Scale: 10},
+		29: {Name: "duration_min", Type: TypeUint16, Units: "min"},
+		30: {Name: "duration", Type: TypeUint32, Units: "s"},
+		31: {Name: "ascent", Type: TypeUint32, Scale: 1000, Units: "m"},
+		32: {Name: "descent", Type: TypeUint32, Scale: 1000, Units: "m"},
+		33: {Name: "moderate_activity_minutes", Type: TypeUint16, Units: "minutes"},
+		34: {Name: "vigorous_activity_minutes", Type: TypeUint16, Units: "minutes"},
+	}},
+	MesgHrv: {Name: "hrv", Fields: map[byte]*FieldProfile{
+		0: {Name: "time", Type: TypeUint16, Array: true, Scale: 1000, Units: "s"},
+	}},
+	MesgLength: {Name: "length", Fields: map[byte]*FieldProfile{
+		0:  {Name: "event", Type: TypeEnum},
+		1:  {Name: "event_type", Type: TypeEnum},
+		2:  {Name: "start_time", Type: TypeUint32, Units: "s"},
+		3:  {Name: "total_elapsed_time", Type: TypeUint32, Scale: 1000, Units: "s"},
+		4:  {Name: "total_timer_time", Type: TypeUint32, Scale: 1000, Units: "s"},
+		5:  {Name: "total_strokes", Type: TypeUint16, Units: "strokes"},
+		6:  {Name: "avg_speed", Type: TypeUint16, Scale: 1000, Units: "m/s"},
+		7:  {Name: "swim_stroke", Type: TypeEnum},
+		9:  {Name: "avg_swimming_cadence", Type: TypeUint8, Units: "strokes/min"},
+		10: {Name: "event_group", Type: TypeUint8},
+		11: {Name: "total_calories", Type: TypeUint16, Units: "kcal"},
+		12: {Name: "length_type", Type: TypeEnum},
+		18: {Name: "player_score", Type: TypeUint16},
+		19: {Name: "opponent_score", Type: TypeUint16},
+		20: {Name: "stroke_count", Type: TypeUint16, Array: true, Units: "counts"},
+		21: {Name: "zone_count", Type: TypeUint16, Array: true, Units: "counts"},
+	}},
+	MesgMonitoringInfo: {Name: "monitoring_info", Fields: map[byte]*FieldProfile{
+		0: {Name: "local_timestamp", Type: TypeUint32, Units: "s"},
+		1: {Name: "activity_type", Type: TypeEnum, Array: true},
+		3: {Name: "cycles_to_distance", Type: TypeUint16, Array: true, Scale: 5000, Units: "m/cycle"},
+		4: {Name: "cycles_to_calories", Type: TypeUint16, Array: true, Scale: 5000, Units: "kcal/cycle"},
+		5: {Name: "resting_metabolic_rate", Type: TypeUint16, Units: "kcal / day"},
+	}},
+	MesgHr: {Name: "hr", Fields: map[byte]*FieldProfile{
+		0: {Name: "fractional_timestamp", Type: TypeUint16, Scale: 32768, Units: "s"},
+		1: {Name: "time256", Type: TypeUint8, Scale: 256, Units: "s", Components: []Component{{Field: 0, Bits: 8, Scale: 256}}},
+		6: {Name: "filtered_bpm", Type: TypeUint8, Array: true, Units: "bpm"},
+		9: {Name: "event_timestamp", Type: TypeUint32, Array: true, Scale: 1024, Units: "s"},
+		10: {Name: "event_timestamp_12", Type: TypeByte, Array: true, Components: []Component{
+			{Field: 9, Bits: 12, Scale: 1024, Accumulate: true},
+			{Field: 9, Bits: 12, Scale: 1024, Accumulate: true},
+			{Field: 9, Bits: 12, Scale: 1024, Accumulate: true},
+			{Field: 9, Bits: 12, Scale: 1024, Accumulate: true},
+			{Field: 9, Bits: 12, Scale: 1024, Accumulate: true},
+			{Field: 9, Bits: 12, Scale: 1024, Accumulate: true},
+			{Field: 9, Bits: 12, Scale: 1024, Accumulate: true},
+			{Field: 9, Bits: 12, Scale: 1024, Accumulate: true},
+			{Field: 9, Bits: 12, Scale: 1024, Accumulate: true},
+			{Field: 9, Bits: 12, Scale: 1024, Accumulate: true},
+		}},
+	}},
+	MesgFieldDescription: {Name: "field_description", Fields: map[byte]*FieldProfile{
+		0:  {Name: "developer_data_index", Type: TypeUint8},
+		1:  {Name: "field_definition_number", Type: TypeUint8},
+		2:  {Name: "fit_base_type_id", Type: TypeUint8},
+		3:  {Name: "field_name", Type: TypeString, Array: true},
+		4:  {Name: "array", Type: TypeUint8},
+		5:  {Name: "components", Type: TypeString},
+		6:  {Name: "scale", Type: TypeUint8},
+		7:  {Name: "offset", Type: TypeSint8},
+		8:  {Name: "units", Type: TypeString, Array: true},
+		9:  {Name: "bits", Type: TypeString},
+		10: {Name: "accumulate", Type: TypeString},
+		13: {Name: "fit_base_unit_id", Type: TypeUint16},
+		14: {Name: "native_mesg_num", Type: TypeUint16},
+		15: {Name: "native_field_num", Type: TypeUint8},
+	}},
+	MesgDeveloperDataID: {Name: "developer_data_id", Fields: map[byte]*FieldProfile{
+		0: {Name: "developer_id", Type: TypeByte, Array: true},
+		1: {Name: "application_id", Type: TypeByte, Array: true},
+		2: {Name: "manufacturer_id", Type: TypeUint16},
+		3: {Name: "developer_data_index", Type: TypeUint8},
+		4: {Name: "application_version", Type: TypeUint32},
+	}},
+	MesgTimeInZone: {Name: "time_in_zone", Fields: map[byte]*FieldProfile{
+		0:  {Name: "reference_mesg", Type: TypeUint16},
+		1:  {Name: "reference_index", Type: TypeUint16},
+		2:  {Name: "time_in_hr_zone", Type: TypeUint32, Array: true, Scale: 1000, Units: "s"},
+		3:  {Name: "time_in_speed_zone", Type: TypeUint32, Array: true, Scale: 1000, Units: "s"},
+		4:  {Name: "time_in_cadence_zone", Type: TypeUint32, Array: true, Scale: 1000, Units: "s"},
+		5:  {Name: "time_in_power_zone", Type: TypeUint32, Array: true, Scale: 1000, Units: "s"},
+		6:  {Name: "hr_zone_high_boundary", Type: TypeUint8, Array: true, Units: "bpm"},
+		7:  {Name: "speed_zone_high_boundary", Type: TypeUint16, Array: true, Scale: 1000, Units: "m/s"},
+		8:  {Name: "cadence_zone_high_bondary", Type: TypeUint8, Array: true, Units: "rpm"},
+		9:  {Name: "power_zone_high_boundary", Type: TypeUint16, Array: true, Units: "watts"},
+		10: {Name: "hr_calc_type", Type: TypeEnum},
+		11: {Name: "max_heart_rate", Type: TypeUint8},
+		12: {Name: "resting_heart_rate", Type: TypeUint8},
+		13: {Name: "threshold_heart_rate", Type: TypeUint8},
+		14: {Name: "pwr_calc_type", Type: TypeEnum},
+		15: {Name: "functional_threshold_power", Type: TypeUint16},
+	}},
+}
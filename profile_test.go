@@ -0,0 +1,103 @@
+package gofit
+
+import (
+	"os"
+	"testing"
+)
+
+func TestSubfieldsFromFile(t *testing.T) {
+	data, err := os.ReadFile("testfiles/test.fit")
+	if err != nil {
+		t.Fatalf("%s\n", err)
+	}
+
+	timers := 0
+	for _, m := range decodeAll(t, data) {
+		switch m.Type {
+		case MesgFileID:
+			// Garmin products have their own numbering
+			if f := m.Field("product"); f == nil || f.Name != "garmin_product" || f.Value != 1836.0 {
+				t.Errorf("got product %+v", f)
+			}
+		case MesgEvent:
+			event, _ := m.Float("event")
+			f := m.Field("data")
+			if event == 0 {
+				timers++
+				if f == nil || f.Name != "timer_trigger" {
+					t.Errorf("got timer event data %+v", f)
+				}
+			} else if f != nil && f.Name != "data" {
+				t.Errorf("got event %f data %+v", event, f)
+			}
+		case MesgSession:
+			// Cycling counts strokes
+			if f := m.Field("total_cycles"); f == nil || f.Name != "total_strokes" || f.Units != "strokes" {
+				t.Errorf("got total cycles %+v", f)
+			}
+		}
+	}
+
+	if timers == 0 {
+		t.Errorf("no timer events")
+	}
+}
+
+func TestSubfieldComponents(t *testing.T) {
+	// Rear gear number, rear teeth, front gear number and front teeth
+	gears := []byte{3, 25, 2, 50}
+
+	data := testFile(
+		testDefinition(0, 0, MesgEvent, [3]byte{0, 1, TypeEnum}, [3]byte{1, 1, TypeEnum}, [3]byte{3, 4, TypeUint32}),
+		testData(0, []byte{43}, []byte{3}, gears),
+		testData(0, []byte{11}, []byte{3}, le32(3700)),
+	)
+
+	messages := decodeAll(t, data)
+
+	gear := messages[0]
+	if f := gear.Field("data"); f == nil || f.Name != "gear_change_data" {
+		t.Errorf("got %+v", f)
+	}
+	for name, want := range map[string]float64{"rear_gear_num": 3, "rear_gear": 25, "front_gear_num": 2, "front_gear": 50} {
+		if v, ok := gear.Float(name); !ok || v != want {
+			t.Errorf("got %s %f, want %f", name, v, want)
+		}
+	}
+
+	// Subfields have their own scale and units
+	battery := messages[1]
+	if v, ok := battery.Float("battery_level"); !ok || v != 3.7 || battery.Field("data").Units != "V" {
+		t.Errorf("got battery level %f", v)
+	}
+}
+
+func TestSubfieldsBySport(t *testing.T) {
+	data := testFile(
+		testDefinition(0, 0, MesgSession, [3]byte{5, 1, TypeEnum}, [3]byte{10, 4, TypeUint32}, [3]byte{18, 1, TypeUint8}),
+		testData(0, []byte{1}, le32(4200), []byte{85}),
+		testData(0, []byte{2}, le32(4200), []byte{85}),
+		testDefinition(1, 0, MesgDeviceInfo, [3]byte{1, 1, TypeUint8}, [3]byte{25, 1, TypeEnum}),
+		testData(1, []byte{120}, []byte{1}),
+	)
+
+	messages := decodeAll(t, data)
+
+	run := messages[0]
+	if f := run.Field("total_cycles"); f == nil || f.Name != "total_strides" {
+		t.Errorf("got %+v", f)
+	}
+	if f := run.Field("avg_cadence"); f == nil || f.Name != "avg_running_cadence" || f.Units != "strides/min" {
+		t.Errorf("got %+v", f)
+	}
+
+	ride := messages[1]
+	if f := ride.Field("avg_cadence"); f == nil || f.Name != "avg_cadence" || f.Units != "rpm" {
+		t.Errorf("got %+v", f)
+	}
+
+	device := messages[2]
+	if v, ok := device.Float("antplus_device_type"); !ok || v != 120 {
+		t.Errorf("got device type %f", v)
+	}
+}
@@ -0,0 +1,382 @@
+package gofit
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// Reader decodes the records of a FIT stream one at a time while reusing
+// its buffers. Each local message type owns a single data buffer that is
+// overwritten whenever a message of that type is read, so decoding a data
+// message does not allocate.
+type Reader struct {
+	input io.Reader
+
+	// Scratch space for the file header and the fixed part of records
+	scratch [14]byte
+
+	// Record data still expected in the current file, and whether a file
+	// header has been read whose CRC has not been consumed yet
+	dataSize uint32
+	dataRead uint32
+	inFile   bool
+
+	// Bytes consumed from input and definition messages read so far
+	offset      int64
+	definitions int
+
+	localMessageTypes [16]*localDefinition
+	view              MessageView
+}
+
+// localDefinition is a definition message together with the buffer its data
+// messages are decoded into.
+type localDefinition struct {
+	DefinitionMesg
+
+	// Sequence number of the definition message, unique within a Reader
+	id int
+
+	// Whether the definition belongs to the current file. Definitions do not
+	// carry over to chained files, but their buffers are kept for reuse.
+	defined bool
+
+	offsets    []int
+	devOffsets []int
+	data       []byte
+}
+
+// MessageView is a data message decoded in place. The byte slices it returns
+// alias the Reader's buffers and are only valid until the next call to Next.
+type MessageView struct {
+	Type uint16
+	Arch byte
+
+	// Offset of the message's record header from the start of the input
+	Offset int64
+
+	// Whether the message had a compressed timestamp header, and the five
+	// bit time offset from that header
+	Compressed bool
+	TimeOffset byte
+
+	def *localDefinition
+}
+
+// NewReader creates a Reader over input. Wrapping a file in a bufio.Reader is
+// recommended since records are read in small pieces.
+func NewReader(input io.Reader) *Reader {
+	return &Reader{input: input}
+}
+
+// Reset discards any buffered state and switches the Reader to input while
+// keeping its buffers, so one Reader can be used for many files.
+func (r *Reader) Reset(input io.Reader) {
+	r.input = input
+	r.dataSize = 0
+	r.dataRead = 0
+	r.inFile = false
+	r.offset = 0
+	r.undefine()
+}
+
+// undefine forgets the definitions of the previous file while keeping their
+// buffers.
+func (r *Reader) undefine() {
+	for _, def := range r.localMessageTypes {
+		if def != nil {
+			def.defined = false
+		}
+	}
+}
+
+// Next returns the next data message in the stream. Definition messages are
+// consumed internally. Chained FIT files are read back to back. io.EOF is
+// returned once the input ends cleanly between files.
+func (r *Reader) Next() (*MessageView, error) {
+	for {
+		if r.dataRead >= r.dataSize {
+			if r.inFile {
+				if err := r.read(r.scratch[:2]); err != nil {
+					return nil, err
+				}
+				r.inFile = false
+			}
+
+			if err := r.readHeader(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		// Read the record header
+		recordOffset := r.offset
+		if err := r.readData(r.scratch[:1]); err != nil {
+			return nil, err
+		}
+		recordHeader := r.scratch[0]
+		localMessageType := recordHeader & 15
+
+		// Compressed timestamp headers are always data messages
+		compressed := (recordHeader & 128) == 128
+		if compressed {
+			localMessageType = (recordHeader >> 5) & 3
+		} else if (recordHeader & 64) == 64 {
+			// This is a definition message
+			if err := r.readDefinition(recordHeader); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		def := r.localMessageTypes[localMessageType]
+		if def == nil || !def.defined {
+			return nil, errors.New("invalid fit file: data message for undefined local message type")
+		}
+
+		if err := r.readData(def.data); err != nil {
+			return nil, err
+		}
+
+		r.view.Type = def.MesgNum
+		r.view.Arch = def.Arch
+		r.view.Offset = recordOffset
+		r.view.Compressed = compressed
+		r.view.TimeOffset = 0
+		if compressed {
+			r.view.TimeOffset = recordHeader & 31
+		}
+		r.view.def = def
+
+		return &r.view, nil
+	}
+}
+
+func (r *Reader) readHeader() error {
+	// A clean io.EOF here means there are no more chained files
+	headerLen := r.scratch[:1]
+	n, err := io.ReadFull(r.input, headerLen)
+	r.offset += int64(n)
+	if err != nil {
+		return err
+	}
+
+	if headerLen[0] < 12 {
+		return errors.New("invalid fit file: header too short")
+	}
+
+	// Protocol version, profile version and data size
+	header := r.scratch[1:12]
+	if err := r.read(header); err != nil {
+		return err
+	}
+	r.dataSize = binary.LittleEndian.Uint32(header[3:7])
+	r.dataRead = 0
+	r.undefine()
+
+	// Seek ahead past the rest of the header now that we know its length
+	for rest := int(headerLen[0]) - 12; rest > 0; {
+		n := rest
+		if n > len(r.scratch) {
+			n = len(r.scratch)
+		}
+		if err := r.read(r.scratch[:n]); err != nil {
+			return err
+		}
+		rest -= n
+	}
+
+	r.inFile = true
+
+	return nil
+}
+
+func (r *Reader) readDefinition(recordHeader byte) error {
+	localMessageType := recordHeader & 15
+
+	def := r.localMessageTypes[localMessageType]
+	if def == nil {
+		def = &localDefinition{}
+		r.localMessageTypes[localMessageType] = def
+	}
+	def.DevDataFlag = recordHeader & 32
+	def.defined = true
+
+	r.definitions++
+	def.id = r.definitions
+
+	// Reserved, architecture, global message number and number of fields
+	fixed := r.scratch[:5]
+	if err := r.readData(fixed); err != nil {
+		return err
+	}
+	def.Arch = fixed[1]
+
+	// Check the arch field to determine the endianness of the global mesg num
+	if fixed[1] == 0 {
+		def.MesgNum = binary.LittleEndian.Uint16(fixed[2:4])
+	} else {
+		def.MesgNum = binary.BigEndian.Uint16(fixed[2:4])
+	}
+
+	// Read the full block of field definitions into the data buffer, which
+	// is resized below anyway, and then parse them
+	fieldDefinitions := def.grow(3 * int(fixed[4]))
+	if err := r.readData(fieldDefinitions); err != nil {
+		return err
+	}
+	if err := parseFieldDefinitions(&def.DefinitionMesg, fieldDefinitions); err != nil {
+		return err
+	}
+
+	def.DevFields = def.DevFields[:0]
+	if def.DevDataFlag > 0 {
+		numDevFields := r.scratch[:1]
+		if err := r.readData(numDevFields); err != nil {
+			return err
+		}
+
+		devFieldDefinitions := def.grow(3 * int(numDevFields[0]))
+		if err := r.readData(devFieldDefinitions); err != nil {
+			return err
+		}
+		if err := parseDevFieldDefinitions(&def.DefinitionMesg, devFieldDefinitions); err != nil {
+			return err
+		}
+	}
+
+	// Lay out the data buffer
+	size := 0
+	def.offsets = def.offsets[:0]
+	for _, field := range def.Fields {
+		def.offsets = append(def.offsets, size)
+		size += int(field.Size)
+	}
+	def.devOffsets = def.devOffsets[:0]
+	for _, field := range def.DevFields {
+		def.devOffsets = append(def.devOffsets, size)
+		size += int(field.Size)
+	}
+	def.data = def.grow(size)
+
+	return nil
+}
+
+// grow returns the definition's data buffer resized to n bytes, reallocating
+// only if its capacity is too small.
+func (d *localDefinition) grow(n int) []byte {
+	if cap(d.data) < n {
+		d.data = make([]byte, n)
+	}
+	return d.data[:n]
+}
+
+// readData reads record data, accounting for it against the data size from
+// the file header.
+func (r *Reader) readData(p []byte) error {
+	if err := r.read(p); err != nil {
+		return err
+	}
+	r.dataRead += uint32(len(p))
+	return nil
+}
+
+// read fills p. Running out of input part way through a file is an error.
+func (r *Reader) read(p []byte) error {
+	n, err := io.ReadFull(r.input, p)
+	r.offset += int64(n)
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// Definition returns the definition the message was decoded with.
+func (m *MessageView) Definition() *DefinitionMesg {
+	return &m.def.DefinitionMesg
+}
+
+// ByteOrder returns the byte order of the message's multi-byte field values.
+func (m *MessageView) ByteOrder() binary.ByteOrder {
+	return byteOrder(m.Arch)
+}
+
+// Bytes returns the raw data of the whole message.
+func (m *MessageView) Bytes() []byte {
+	return m.def.data
+}
+
+// Field returns the raw bytes of field num, or nil if the message does not
+// contain it.
+func (m *MessageView) Field(num byte) []byte {
+	for i, field := range m.def.Fields {
+		if field.Number == num {
+			offset := m.def.offsets[i]
+			return m.def.data[offset : offset+int(field.Size)]
+		}
+	}
+	return nil
+}
+
+// DevField returns the raw bytes of developer field num from the developer
+// data index idx, or nil if the message does not contain it.
+func (m *MessageView) DevField(idx, num byte) []byte {
+	for i, field := range m.def.DevFields {
+		if field.DevDataIdx == idx && field.Number == num {
+			offset := m.def.devOffsets[i]
+			return m.def.data[offset : offset+int(field.Size)]
+		}
+	}
+	return nil
+}
+
+// DataMessage copies the view into a DataMessage that remains valid after
+// the next call to Next.
+func (m *MessageView) DataMessage() DataMessage {
+	return newDataMessage(&m.def.DefinitionMesg, m.def.data)
+}
+
+// newDataMessage splits data into the fields of def. Every field shares a
+// single copy of the message data.
+func newDataMessage(def *DefinitionMesg, data []byte) DataMessage {
+	dataMsg := DataMessage{}
+	dataMsg.Type = def.MesgNum
+	dataMsg.Arch = def.Arch
+
+	buf := make([]byte, len(data))
+	copy(buf, data)
+
+	offset := 0
+	dataMsg.Fields = make(map[byte][]byte, len(def.Fields))
+	for _, field := range def.Fields {
+		end := offset + int(field.Size)
+		dataMsg.Fields[field.Number] = buf[offset:end:end]
+		offset = end
+	}
+
+	dataMsg.DevFields = make(map[byte]map[byte][]byte)
+	for _, field := range def.DevFields {
+		if dataMsg.DevFields[field.DevDataIdx] == nil {
+			dataMsg.DevFields[field.DevDataIdx] = make(map[byte][]byte)
+		}
+
+		end := offset + int(field.Size)
+		dataMsg.DevFields[field.DevDataIdx][field.Number] = buf[offset:end:end]
+		offset = end
+	}
+
+	return dataMsg
+}
+
+// dataSize returns the number of bytes in a data message for def.
+func (def *DefinitionMesg) dataSize() int {
+	size := 0
+	for _, field := range def.Fields {
+		size += int(field.Size)
+	}
+	for _, field := range def.DevFields {
+		size += int(field.Size)
+	}
+	return size
+}
@@ -0,0 +1,284 @@
+package gofit
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func readTestFiles(tb testing.TB) map[string][]byte {
+	paths, err := filepath.Glob("testfiles/*.fit")
+	if err != nil || len(paths) == 0 {
+		tb.Fatalf("no test files: %v", err)
+	}
+
+	files := make(map[string][]byte)
+	for _, path := range paths {
+		data, ferr := os.ReadFile(path)
+		if ferr != nil {
+			tb.Fatalf("%s\n", ferr)
+		}
+		files[path] = data
+	}
+
+	return files
+}
+
+// Message counts and a digest of every data message as the original
+// parser, before the Reader was introduced, decoded each test file.
+// bad.fit is cut short by an error in both; the original parser decoded
+// more messages from the garbage that follows the file, where the Reader
+// stops at a data message for an undefined local message type.
+var parserGolden = []struct {
+	file                  string
+	messages              int
+	records, laps, events int
+	digest                uint32
+	eof                   bool
+}{
+	{"21497.fit", 4998, 4899, 1, 8, 0x700afeb4, true},
+	{"bad.fit", 3597, 1822, 1, 8, 0x5c08bea5, false},
+	{"devdata.fit", 7634, 3737, 9, 5, 0x1a723e63, true},
+	{"fit2-2.fit", 722, 674, 1, 4, 0x39afd3fd, true},
+	{"fit2.fit", 967, 907, 3, 5, 0xf5ea0c96, true},
+	{"qollector.fit", 4658, 4051, 0, 0, 0x2b59132c, true},
+	{"test.fit", 10375, 9905, 1, 290, 0x6824ddb9, true},
+	{"test2.fit", 1341, 1309, 1, 3, 0x4bc54101, true},
+}
+
+// messageDigest serialises the type, architecture and raw fields of m in a
+// fixed order for hashing.
+func messageDigest(m DataMessage) []byte {
+	var b bytes.Buffer
+	binary.Write(&b, binary.LittleEndian, m.Type)
+	b.WriteByte(m.Arch)
+
+	var nums []int
+	for num := range m.Fields {
+		nums = append(nums, int(num))
+	}
+	sort.Ints(nums)
+	for _, num := range nums {
+		value := m.Fields[byte(num)]
+		b.Write([]byte{byte(num), byte(len(value))})
+		b.Write(value)
+	}
+
+	var indices []int
+	for idx := range m.DevFields {
+		indices = append(indices, int(idx))
+	}
+	sort.Ints(indices)
+	for _, idx := range indices {
+		nums = nums[:0]
+		for num := range m.DevFields[byte(idx)] {
+			nums = append(nums, int(num))
+		}
+		sort.Ints(nums)
+		for _, num := range nums {
+			value := m.DevFields[byte(idx)][byte(num)]
+			b.Write([]byte{byte(idx), byte(num), byte(len(value))})
+			b.Write(value)
+		}
+	}
+
+	return b.Bytes()
+}
+
+func TestReaderMatchesParse(t *testing.T) {
+	for _, want := range parserGolden {
+		data, err := os.ReadFile(filepath.Join("testfiles", want.file))
+		if err != nil {
+			t.Fatalf("%s\n", err)
+		}
+
+		r := NewReader(bytes.NewReader(data))
+		h := crc32.NewIEEE()
+		messages := 0
+		types := make(map[uint16]int)
+		for {
+			m, rerr := r.Next()
+			if rerr != nil {
+				err = rerr
+				break
+			}
+
+			got := m.DataMessage()
+			h.Write(messageDigest(got))
+			types[got.Type]++
+			messages++
+
+			// The view and the copy agree
+			for num, value := range got.Fields {
+				if !bytes.Equal(m.Field(num), value) {
+					t.Errorf("%s: message %d: field %d differs", want.file, messages, num)
+				}
+			}
+		}
+
+		if (err == io.EOF) != want.eof {
+			t.Errorf("%s: got error %v", want.file, err)
+		}
+		if messages != want.messages || types[MesgRecord] != want.records || types[MesgLap] != want.laps || types[MesgEvent] != want.events {
+			t.Errorf("%s: got %d messages, %d records, %d laps and %d events", want.file, messages, types[MesgRecord], types[MesgLap], types[MesgEvent])
+		}
+		if digest := h.Sum32(); digest != want.digest {
+			t.Errorf("%s: got digest %08x, want %08x", want.file, digest, want.digest)
+		}
+	}
+}
+
+func TestReaderEOF(t *testing.T) {
+	data, err := os.ReadFile("testfiles/test2.fit")
+	if err != nil {
+		t.Fatalf("%s\n", err)
+	}
+
+	// A complete file ends cleanly
+	r := NewReader(bytes.NewReader(data))
+	for err == nil {
+		_, err = r.Next()
+	}
+	if err != io.EOF {
+		t.Errorf("got %v, want io.EOF", err)
+	}
+
+	// A truncated one does not
+	r = NewReader(bytes.NewReader(data[:len(data)/2]))
+	for err = nil; err == nil; {
+		_, err = r.Next()
+	}
+	if err != io.ErrUnexpectedEOF {
+		t.Errorf("got %v, want io.ErrUnexpectedEOF", err)
+	}
+}
+
+func TestReaderChained(t *testing.T) {
+	data, err := os.ReadFile("testfiles/test2.fit")
+	if err != nil {
+		t.Fatalf("%s\n", err)
+	}
+
+	count := func(input []byte) int {
+		n := 0
+		r := NewReader(bytes.NewReader(input))
+		for {
+			if _, err := r.Next(); err != nil {
+				return n
+			}
+			n++
+		}
+	}
+
+	single := count(data)
+	if chained := count(append(append([]byte{}, data...), data...)); chained != 2*single {
+		t.Errorf("got %d messages from two chained files, want %d", chained, 2*single)
+	}
+}
+
+func TestReaderForgetsDefinitions(t *testing.T) {
+	data, err := os.ReadFile("testfiles/test2.fit")
+	if err != nil {
+		t.Fatalf("%s\n", err)
+	}
+
+	// A file whose only record is a data message for a type it never defines
+	undefined := testFile(testData(0, []byte{1, 2, 3}))
+
+	readAll := func(r *Reader) error {
+		for {
+			if _, err := r.Next(); err != nil {
+				return err
+			}
+		}
+	}
+
+	fresh := readAll(NewReader(bytes.NewReader(undefined)))
+	if fresh == nil || fresh == io.EOF {
+		t.Fatalf("got %v from a fresh reader", fresh)
+	}
+
+	// After a Reset
+	r := NewReader(bytes.NewReader(data))
+	if err := readAll(r); err != io.EOF {
+		t.Fatalf("%s\n", err)
+	}
+	r.Reset(bytes.NewReader(undefined))
+	if err := readAll(r); err == nil || err.Error() != fresh.Error() {
+		t.Errorf("got %v after Reset, want %v", err, fresh)
+	}
+
+	// And in a chained file
+	chained := append(append([]byte(nil), data...), undefined...)
+	if err := readAll(NewReader(bytes.NewReader(chained))); err == nil || err.Error() != fresh.Error() {
+		t.Errorf("got %v in a chained file, want %v", err, fresh)
+	}
+}
+
+func TestReaderAllocs(t *testing.T) {
+	data, err := os.ReadFile("testfiles/test2.fit")
+	if err != nil {
+		t.Fatalf("%s\n", err)
+	}
+
+	input := bytes.NewReader(data)
+	r := NewReader(input)
+
+	// Read through once so every definition buffer has been allocated
+	for err == nil {
+		_, err = r.Next()
+	}
+
+	allocs := testing.AllocsPerRun(10, func() {
+		input.Reset(data)
+		r.Reset(input)
+		for {
+			if _, err := r.Next(); err != nil {
+				break
+			}
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("got %v allocations per file, want 0", allocs)
+	}
+}
+
+func BenchmarkParse(b *testing.B) {
+	files := readTestFiles(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		for _, data := range files {
+			fit := NewFIT(bytes.NewReader(data))
+			fit.Parse()
+			for range fit.MessageChan {
+			}
+		}
+	}
+}
+
+func BenchmarkReader(b *testing.B) {
+	files := readTestFiles(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	input := bytes.NewReader(nil)
+	r := NewReader(input)
+	for i := 0; i < b.N; i++ {
+		for _, data := range files {
+			input.Reset(data)
+			r.Reset(input)
+			for {
+				if _, err := r.Next(); err != nil {
+					break
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package gofit
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// Repair fixes the framing of a FIT file that other software rejects: a
+// wrong data size in the header, a wrong header or file CRC, a missing
+// ".FIT" signature, or a record cut short at the end of the file. It
+// returns the repaired file.
+//
+// The records are walked from the header to find the true length of the
+// data. If the data size in the header ends on a record boundary and is
+// followed by a file CRC that ends the input or precedes another file
+// header, it is trusted, and any chained files that follow are repaired in
+// turn, while trailing bytes after them that do not start with a file
+// header are dropped. Otherwise records are read for as long as they are
+// complete, an incomplete record at the end is dropped along with the old
+// file CRC, and the rest of the input is taken to be the last file. The
+// records themselves are copied unchanged.
+func Repair(data []byte) ([]byte, error) {
+	var repaired []byte
+
+	for len(data) > 0 {
+		headerLen := int(data[0])
+		if repaired != nil && !startsFile(data) {
+			// Trailing bytes after a complete file that do not start
+			// another one are dropped
+			return repaired, nil
+		}
+		if headerLen < 12 || headerLen > len(data) {
+			return nil, errors.New("invalid fit file: header too short")
+		}
+
+		header := append([]byte(nil), data[:headerLen]...)
+		body := data[headerLen:]
+
+		// The declared size is compared to the input before it is
+		// converted, as a corrupt one may not fit in an int
+		declared := uint64(binary.LittleEndian.Uint32(header[4:8]))
+		size := 0
+		rest := []byte(nil)
+
+		// A size that ends on a record boundary may still be too small, as
+		// one of 0 always does, so it is only trusted if the file CRC after
+		// it ends the input or is followed by another file
+		if declared+2 <= uint64(len(body)) && scanRecords(body[:declared]) == int(declared) && (declared+2 == uint64(len(body)) || startsFile(body[declared+2:])) {
+			size = int(declared)
+			rest = body[size+2:]
+		} else {
+			// Walk to the last complete record, but not through an old
+			// file CRC that happens to read as a record. Two bytes at the
+			// end that read as a record are taken to be the CRC unless the
+			// header says they are data.
+			var prev int
+			size, prev = scanRecordsFrom(body)
+			if size == len(body) && prev == len(body)-2 && declared != uint64(len(body)) {
+				size = prev
+			}
+		}
+
+		binary.LittleEndian.PutUint32(header[4:8], uint32(size))
+		copy(header[8:12], ".FIT")
+		if headerLen >= 14 {
+			binary.LittleEndian.PutUint16(header[12:14], CRC(header[:12]))
+		}
+
+		crc := updateCRC(CRC(header), body[:size])
+
+		repaired = append(repaired, header...)
+		repaired = append(repaired, body[:size]...)
+		repaired = append(repaired, byte(crc), byte(crc>>8))
+
+		data = rest
+	}
+
+	return repaired, nil
+}
+
+// startsFile reports whether data starts with a FIT file header.
+func startsFile(data []byte) bool {
+	return len(data) >= 12 && int(data[0]) >= 12 && int(data[0]) <= len(data) && string(data[8:12]) == ".FIT"
+}
+
+// scanRecords returns the length of the complete, valid records at the
+// start of data.
+func scanRecords(data []byte) int {
+	end, _ := scanRecordsFrom(data)
+	return end
+}
+
+// scanRecordsFrom walks the records at the start of data for as long as
+// they are complete and valid. It returns the offset at which the last of
+// them ends, and the offset at which the one before it ends.
+func scanRecordsFrom(data []byte) (end, prev int) {
+	var sizes [16]int
+	var defined [16]bool
+
+	for end < len(data) {
+		recordHeader := data[end]
+		next := end + 1
+
+		switch {
+		case recordHeader&0x80 == 0x80:
+			// Compressed timestamp header
+			local := (recordHeader >> 5) & 3
+			if !defined[local] {
+				return end, prev
+			}
+			next += sizes[local]
+		case recordHeader&0x40 == 0x40:
+			// Reserved, architecture, global message number and number of
+			// fields, then the field definitions
+			if next+5 > len(data) || data[next+1] > 1 {
+				return end, prev
+			}
+			fields := int(data[next+4])
+			defs := next + 5
+			next = defs + 3*fields
+			if next > len(data) {
+				return end, prev
+			}
+
+			size := 0
+			for i := 0; i < fields; i++ {
+				size += int(data[defs+3*i+1])
+			}
+
+			if recordHeader&0x20 == 0x20 {
+				if next >= len(data) {
+					return end, prev
+				}
+				devFields := int(data[next])
+				devDefs := next + 1
+				next = devDefs + 3*devFields
+				if next > len(data) {
+					return end, prev
+				}
+				for i := 0; i < devFields; i++ {
+					size += int(data[devDefs+3*i+1])
+				}
+			}
+
+			local := recordHeader & 15
+			sizes[local] = size
+			defined[local] = true
+		default:
+			local := recordHeader & 15
+			if !defined[local] {
+				return end, prev
+			}
+			next += sizes[local]
+		}
+
+		if next > len(data) {
+			return end, prev
+		}
+		end, prev = next, end
+	}
+
+	return end, prev
+}
@@ -0,0 +1,145 @@
+package gofit
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"testing"
+)
+
+func checkRepaired(t *testing.T, name string, repaired []byte, records int) {
+	t.Helper()
+
+	if crc := CRC(repaired); crc != 0 {
+		t.Errorf("%s: got file CRC %04x", name, crc)
+	}
+	if size := binary.LittleEndian.Uint32(repaired[4:8]); int(size) != len(repaired)-int(repaired[0])-2 {
+		t.Errorf("%s: got data size %d for %d bytes", name, size, len(repaired))
+	}
+	if repaired[0] >= 14 && binary.LittleEndian.Uint16(repaired[12:14]) != CRC(repaired[:12]) {
+		t.Errorf("%s: wrong header CRC", name)
+	}
+
+	a, err := ReadActivity(bytes.NewReader(repaired))
+	if err != nil {
+		t.Fatalf("%s: %s", name, err)
+	}
+	if len(a.Records) != records {
+		t.Errorf("%s: got %d records, want %d", name, len(a.Records), records)
+	}
+}
+
+func TestRepair(t *testing.T) {
+	data, err := os.ReadFile("testfiles/test2.fit")
+	if err != nil {
+		t.Fatalf("%s\n", err)
+	}
+	records := len(readActivity(t, "testfiles/test2.fit").Records)
+
+	// A valid file comes back with the same records
+	repaired, err := Repair(data)
+	if err != nil {
+		t.Fatalf("%s\n", err)
+	}
+	// The header CRC of this file is left as zero, so only the records
+	// are the same
+	if !bytes.Equal(repaired[14:len(repaired)-2], data[14:len(data)-2]) {
+		t.Errorf("valid file changed")
+	}
+	checkRepaired(t, "valid", repaired, records)
+
+	// Wrong data size and CRCs
+	broken := append([]byte(nil), data...)
+	binary.LittleEndian.PutUint32(broken[4:8], uint32(len(data)+1000))
+	broken[12], broken[len(broken)-1] = broken[12]+1, broken[len(broken)-1]+1
+	repaired, err = Repair(broken)
+	if err != nil {
+		t.Fatalf("%s\n", err)
+	}
+	checkRepaired(t, "bad size", repaired, records)
+	if !bytes.Equal(repaired[14:len(repaired)-2], data[14:len(data)-2]) {
+		t.Errorf("records changed")
+	}
+
+	// Data size too small, which would cut the file short, whether or not
+	// it ends on a record boundary
+	boundary, _ := scanRecordsFrom(data[14:1000])
+	for _, size := range []uint32{100, 0, uint32(boundary), 0xFFFFFFFE} {
+		binary.LittleEndian.PutUint32(broken[4:8], size)
+		repaired, _ = Repair(broken)
+		checkRepaired(t, fmt.Sprintf("small size %d", size), repaired, records)
+	}
+
+	// Cut off part way through the last record, without a CRC
+	cut := append([]byte(nil), data[:len(data)-10]...)
+	repaired, _ = Repair(cut)
+	checkRepaired(t, "truncated", repaired, records)
+	if len(repaired) >= len(data) {
+		t.Errorf("incomplete record kept")
+	}
+}
+
+func TestRepairCRCAsRecord(t *testing.T) {
+	// The zero CRC is wrong, and reads as a heart rate record of 0
+	data := testFile(
+		testDefinition(0, 0, MesgRecord, [3]byte{3, 1, TypeUint8}),
+		testData(0, []byte{100}),
+		testData(0, []byte{101}),
+	)
+	binary.LittleEndian.PutUint32(data[4:8], 1000)
+
+	repaired, err := Repair(data)
+	if err != nil {
+		t.Fatalf("%s\n", err)
+	}
+	checkRepaired(t, "crc as record", repaired, 2)
+	if len(repaired) != len(data) {
+		t.Errorf("got %d bytes, want %d", len(repaired), len(data))
+	}
+}
+
+func TestRepairChained(t *testing.T) {
+	data, err := os.ReadFile("testfiles/test2.fit")
+	if err != nil {
+		t.Fatalf("%s\n", err)
+	}
+	records := len(readActivity(t, "testfiles/test2.fit").Records)
+
+	// The second file has lost its CRC
+	chained := append(append([]byte(nil), data...), data[:len(data)-2]...)
+	repaired, err := Repair(chained)
+	if err != nil {
+		t.Fatalf("%s\n", err)
+	}
+	if len(repaired) != 2*len(data) || CRC(repaired[len(data):]) != 0 {
+		t.Errorf("got %d bytes", len(repaired))
+	}
+
+	a, err := ReadActivity(bytes.NewReader(repaired))
+	if err != nil || len(a.Records) != 2*records {
+		t.Errorf("got %d records: %v", len(a.Records), err)
+	}
+}
+
+func TestRepairBadHeader(t *testing.T) {
+	if _, err := Repair([]byte{4, 0, 0, 0}); err == nil {
+		t.Errorf("expected an error")
+	}
+}
+
+func TestRepairBadFile(t *testing.T) {
+	data, err := os.ReadFile("testfiles/bad.fit")
+	if err != nil {
+		t.Fatalf("%s\n", err)
+	}
+
+	// Reading stops with an error part way through the original
+	a, _ := ReadActivity(bytes.NewReader(data))
+
+	repaired, err := Repair(data)
+	if err != nil {
+		t.Fatalf("%s\n", err)
+	}
+	checkRepaired(t, "bad.fit", repaired, len(a.Records))
+}
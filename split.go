@@ -0,0 +1,73 @@
+package gofit
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// Split divides the activity at the given times into consecutive parts,
+// each a complete activity as Trim makes it with its own file_id, laps,
+// sessions and activity message. A record at a split time starts the next
+// part. Each part's file_id is given the part's start as its time_created,
+// adding the field if the file has none, so the files can be told apart.
+// It returns an error if any part would have no records.
+func (a *Activity) Split(at ...time.Time) ([]*Activity, error) {
+	if len(a.Records) == 0 {
+		return nil, errors.New("no records to split")
+	}
+
+	times := append([]time.Time(nil), at...)
+	sort.Slice(times, func(i, j int) bool {
+		return times[i].Before(times[j])
+	})
+
+	start := a.Records[0].Time
+	end := a.Records[len(a.Records)-1].Time
+
+	parts := make([]*Activity, 0, len(times)+1)
+	for i := 0; i <= len(times); i++ {
+		partEnd := end
+		if i < len(times) {
+			partEnd = times[i].Add(-time.Nanosecond)
+		}
+
+		part, err := a.Trim(start, partEnd)
+		if err != nil {
+			return nil, err
+		}
+		if part.FileID != nil {
+			if err := part.FileID.addFloat("time_created", fitSeconds(part.Records[0].Time)); err != nil {
+				return nil, err
+			}
+		}
+		parts = append(parts, part)
+
+		if i < len(times) {
+			start = times[i]
+		}
+	}
+
+	return parts, nil
+}
+
+// SplitLaps divides the activity before each of the given laps, counting
+// from zero, as Split does at their start times. With no laps given it is
+// split at every lap.
+func (a *Activity) SplitLaps(laps ...int) ([]*Activity, error) {
+	if len(laps) == 0 {
+		for i := 1; i < len(a.Laps); i++ {
+			laps = append(laps, i)
+		}
+	}
+
+	times := make([]time.Time, 0, len(laps))
+	for _, i := range laps {
+		if i < 0 || i >= len(a.Laps) {
+			return nil, errors.New("lap out of range")
+		}
+		times = append(times, summaryStart(a.Laps[i]))
+	}
+
+	return a.Split(times...)
+}
@@ -0,0 +1,105 @@
+package gofit
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestSplitLaps(t *testing.T) {
+	a := readActivity(t, "testfiles/devdata.fit")
+
+	parts, err := a.SplitLaps(3, 6)
+	if err != nil {
+		t.Fatalf("%s\n", err)
+	}
+	if len(parts) != 3 {
+		t.Fatalf("got %d parts", len(parts))
+	}
+
+	records := 0
+	var created []float64
+	for i, part := range parts {
+		var buf bytes.Buffer
+		if err := WriteActivity(&buf, part); err != nil {
+			t.Fatalf("%s\n", err)
+		}
+		if crc := CRC(buf.Bytes()); crc != 0 {
+			t.Errorf("part %d: got file CRC %04x", i, crc)
+		}
+
+		b, err := ReadActivity(&buf)
+		if err != nil {
+			t.Fatalf("%s\n", err)
+		}
+		if b.FileID == nil || b.Activity == nil || len(b.Sessions) != 1 || len(b.Laps) != 3 {
+			t.Fatalf("part %d: got %d sessions and %d laps", i, len(b.Sessions), len(b.Laps))
+		}
+
+		// Each part starts with the lap it was split at
+		lapStart := summaryStart(a.Laps[3*i])
+		if start, _ := b.Sessions[0].Timestamp("start_time"); start.Sub(lapStart) > time.Second {
+			t.Errorf("part %d: starts at %s, lap at %s", i, start, lapStart)
+		}
+		if laps, _ := b.Sessions[0].Float("num_laps"); laps != 3 {
+			t.Errorf("part %d: got %f laps", i, laps)
+		}
+
+		v, _ := b.FileID.Float("time_created")
+		created = append(created, v)
+		records += len(b.Records)
+	}
+
+	if records != len(a.Records) {
+		t.Errorf("got %d records in all, want %d", records, len(a.Records))
+	}
+	if created[0] == created[1] || created[1] == created[2] {
+		t.Errorf("parts share a file id: %v", created)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	a := readActivity(t, "testfiles/test2.fit")
+	at := a.Records[500].Time
+
+	parts, err := a.Split(at)
+	if err != nil {
+		t.Fatalf("%s\n", err)
+	}
+	if len(parts) != 2 || len(parts[0].Records) != 500 || !parts[1].Records[0].Time.Equal(at) {
+		t.Fatalf("got %d parts", len(parts))
+	}
+
+	// Parts can be told apart even if the file_id has no time_created
+	if i := a.FileID.fieldIndex(4); i >= 0 {
+		a.FileID.Fields = append(a.FileID.Fields[:i], a.FileID.Fields[i+1:]...)
+	}
+	if parts, err = a.Split(at); err != nil {
+		t.Fatalf("%s\n", err)
+	}
+	var created [2]float64
+	for i, part := range parts {
+		var buf bytes.Buffer
+		if err := WriteActivity(&buf, part); err != nil {
+			t.Fatalf("%s\n", err)
+		}
+		b, err := ReadActivity(&buf)
+		if err != nil {
+			t.Fatalf("%s\n", err)
+		}
+		created[i], _ = b.FileID.Float("time_created")
+		if created[i] != fitSeconds(part.Records[0].Time) {
+			t.Errorf("part %d created at %f", i, created[i])
+		}
+	}
+	if created[0] == created[1] {
+		t.Errorf("parts share a file id: %v", created)
+	}
+
+	if _, err := a.Split(a.Records[0].Time.Add(-time.Hour)); err == nil {
+		t.Errorf("expected an error for an empty part")
+	}
+	if _, err := a.SplitLaps(1); err == nil {
+		t.Errorf("expected an error for a missing lap")
+	}
+}
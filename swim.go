@@ -0,0 +1,254 @@
+package gofit
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// SwimStroke is the stroke swum over a length.
+type SwimStroke byte
+
+const (
+	StrokeFreestyle SwimStroke = iota
+	StrokeBackstroke
+	StrokeBreaststroke
+	StrokeButterfly
+	StrokeDrill
+	StrokeMixed
+	StrokeIM
+)
+
+var swimStrokeNames = []string{"freestyle", "backstroke", "breaststroke", "butterfly", "drill", "mixed", "im"}
+
+// String returns the profile name of the stroke.
+func (s SwimStroke) String() string {
+	if int(s) < len(swimStrokeNames) {
+		return swimStrokeNames[s]
+	}
+	return "unknown"
+}
+
+// Values of length.length_type
+const (
+	lengthIdle   = 0
+	lengthActive = 1
+)
+
+// SwimLength summarises one length of a pool swim.
+type SwimLength struct {
+	Start    time.Time
+	Duration time.Duration
+
+	// Idle lengths are the rests between intervals and have no stroke
+	Active  bool
+	Stroke  SwimStroke
+	Strokes int
+
+	// Average speed in m/s
+	Speed float64
+
+	// Average of the heart rate samples recorded during the length, or 0 if
+	// there are none
+	HeartRate float64
+}
+
+// SwimInterval is a run of active lengths between rests.
+type SwimInterval struct {
+	Lengths []SwimLength
+
+	Start    time.Time
+	Duration time.Duration
+
+	// Distance in meters, from the pool length
+	Distance float64
+	Strokes  int
+
+	// The stroke of every length, or StrokeMixed if they differ
+	Stroke SwimStroke
+
+	// Time spent idle after the interval
+	Rest time.Duration
+}
+
+// PoolLength returns the length of the pool in meters from the session, or
+// false if this is not a pool swim.
+func (a *Activity) PoolLength() (float64, bool) {
+	for _, s := range a.Sessions {
+		if l, ok := s.Float("pool_length"); ok && l > 0 {
+			return l, true
+		}
+	}
+	return 0, false
+}
+
+// SwimLengths summarises the activity's length messages in order. Their
+// heart rates come from the buffered samples of hr messages, see
+// HeartRateSamples.
+func (a *Activity) SwimLengths() []SwimLength {
+	samples := a.HeartRateSamples()
+
+	lengths := make([]SwimLength, 0, len(a.Lengths))
+	for _, m := range a.Lengths {
+		start, ok := m.Timestamp("start_time")
+		if !ok {
+			continue
+		}
+		elapsed, _ := m.Float("total_elapsed_time")
+		lengthType, _ := m.Float("length_type")
+		stroke, _ := m.Float("swim_stroke")
+		strokes, _ := m.Float("total_strokes")
+		speed, _ := m.Float("avg_speed")
+
+		l := SwimLength{
+			Start:    start,
+			Duration: time.Duration(elapsed * float64(time.Second)),
+			Active:   lengthType == lengthActive,
+			Speed:    speed,
+		}
+		if l.Active {
+			l.Stroke = SwimStroke(stroke)
+			l.Strokes = int(strokes)
+		}
+		l.HeartRate = averageHeartRate(samples, l.Start, l.Start.Add(l.Duration))
+
+		lengths = append(lengths, l)
+	}
+	return lengths
+}
+
+// SwimIntervals groups the activity's active lengths into intervals
+// separated by idle lengths.
+func (a *Activity) SwimIntervals() []SwimInterval {
+	pool, _ := a.PoolLength()
+
+	var intervals []SwimInterval
+	var current *SwimInterval
+	for _, l := range a.SwimLengths() {
+		if !l.Active {
+			if current != nil {
+				current.Rest += l.Duration
+			}
+			continue
+		}
+
+		if current == nil || current.Rest > 0 {
+			intervals = append(intervals, SwimInterval{Start: l.Start, Stroke: l.Stroke})
+			current = &intervals[len(intervals)-1]
+		}
+
+		current.Lengths = append(current.Lengths, l)
+		current.Duration = l.Start.Add(l.Duration).Sub(current.Start)
+		current.Distance += pool
+		current.Strokes += l.Strokes
+		if l.Stroke != current.Stroke {
+			current.Stroke = StrokeMixed
+		}
+	}
+	return intervals
+}
+
+// HeartRateSample is a single heart rate reading.
+type HeartRateSample struct {
+	Time time.Time
+	BPM  float64
+}
+
+// HeartRateSamples returns the heart rate samples of the activity's hr
+// messages in time order. Straps that cannot transmit underwater buffer
+// their readings and deliver them later in hr messages, each reading timed
+// by an event_timestamp. These are placed on the activity's timeline
+// relative to the last hr message with a timestamp, whose first
+// event_timestamp was taken at that time.
+func (a *Activity) HeartRateSamples() []HeartRateSample {
+	var samples []HeartRateSample
+
+	var anchor time.Time
+	var anchorEvent float64
+	anchored := false
+
+	for _, m := range a.Messages {
+		if m.Type != MesgHr {
+			continue
+		}
+
+		events := m.Floats("event_timestamp")
+		bpm := m.Floats("filtered_bpm")
+
+		if !m.Time.IsZero() && len(events) > 0 && !math.IsNaN(events[0]) {
+			fraction, _ := m.Float("fractional_timestamp")
+			anchor = m.Time.Add(time.Duration(fraction * float64(time.Second)))
+			anchorEvent = events[0]
+			anchored = true
+		}
+		if !anchored {
+			continue
+		}
+
+		for i := 0; i < len(events) && i < len(bpm); i++ {
+			if math.IsNaN(events[i]) || math.IsNaN(bpm[i]) {
+				continue
+			}
+			offset := time.Duration((events[i] - anchorEvent) * float64(time.Second))
+			samples = append(samples, HeartRateSample{anchor.Add(offset), bpm[i]})
+		}
+	}
+
+	sort.SliceStable(samples, func(i, j int) bool {
+		return samples[i].Time.Before(samples[j].Time)
+	})
+	return samples
+}
+
+// averageHeartRate returns the average of the samples in (start, end], or 0
+// if there are none. The samples must be in time order.
+func averageHeartRate(samples []HeartRateSample, start, end time.Time) float64 {
+	i := sort.Search(len(samples), func(i int) bool {
+		return samples[i].Time.After(start)
+	})
+
+	sum, n := 0.0, 0
+	for ; i < len(samples) && !samples[i].Time.After(end); i++ {
+		sum += samples[i].BPM
+		n++
+	}
+	if n == 0 {
+		return 0
+	}
+	return sum / float64(n)
+}
+
+// MergeHeartRate adds the buffered heart rate samples of hr messages to the
+// records that have no heart rate of their own. Each record takes the
+// average of the samples since the record before it.
+func (a *Activity) MergeHeartRate() {
+	samples := a.HeartRateSamples()
+	if len(samples) == 0 {
+		return
+	}
+
+	var previous time.Time
+	for _, r := range a.Records {
+		start := previous
+		previous = r.Time
+		if start.IsZero() {
+			start = r.Time.Add(-time.Second)
+		}
+
+		if _, ok := r.Float("heart_rate"); ok {
+			continue
+		}
+
+		bpm := averageHeartRate(samples, start, r.Time)
+		if bpm == 0 {
+			continue
+		}
+
+		hr := Field{Num: 3, Name: "heart_rate", Units: "bpm", Type: TypeUint8, Value: math.Round(bpm)}
+		if f := r.FieldNum(3); f != nil {
+			*f = hr
+		} else {
+			r.Fields = append(r.Fields, hr)
+		}
+	}
+}
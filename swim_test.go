@@ -0,0 +1,103 @@
+package gofit
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+)
+
+func swimFile() []byte {
+	const full = 1000000000
+	const event = 5000 * 1024
+
+	length := func(start, elapsed uint32, strokes uint16, stroke, lengthType byte) []byte {
+		return testData(1, le32(full+start), le32(elapsed*1000), le16(strokes), []byte{stroke, lengthType})
+	}
+	hr := func(events [3]uint32, bpm ...byte) []byte {
+		return testData(3, bpm, le32(event+events[0]*1024), le32(event+events[1]*1024), le32(event+events[2]*1024))
+	}
+
+	return testFile(
+		testDefinition(0, 0, MesgSession, [3]byte{253, 4, TypeUint32}, [3]byte{44, 2, TypeUint16}),
+		testData(0, le32(full), le16(2500)),
+
+		testDefinition(2, 0, MesgHr, [3]byte{253, 4, TypeUint32}, [3]byte{0, 2, TypeUint16}, [3]byte{6, 3, TypeUint8}, [3]byte{9, 12, TypeUint32}),
+		testData(2, le32(full), le16(0), []byte{100, 102, 104}, le32(event), le32(event+1024), le32(event+2*1024)),
+
+		testDefinition(1, 0, MesgLength,
+			[3]byte{2, 4, TypeUint32}, [3]byte{3, 4, TypeUint32}, [3]byte{5, 2, TypeUint16}, [3]byte{7, 1, TypeEnum}, [3]byte{12, 1, TypeEnum}),
+		length(0, 30, 20, byte(StrokeFreestyle), lengthActive),
+		length(30, 32, 22, byte(StrokeFreestyle), lengthActive),
+		length(62, 20, 0xFFFF, 0xFF, lengthIdle),
+		length(82, 40, 15, byte(StrokeBreaststroke), lengthActive),
+
+		testDefinition(4, 0, MesgRecord, [3]byte{253, 4, TypeUint32}, [3]byte{3, 1, TypeUint8}),
+		testData(4, le32(full), []byte{0xFF}),
+		testData(4, le32(full+10), []byte{0xFF}),
+		testData(4, le32(full+40), []byte{77}),
+
+		// The buffered readings arrive after the lengths they belong to
+		testDefinition(3, 0, MesgHr, [3]byte{6, 3, TypeUint8}, [3]byte{9, 12, TypeUint32}),
+		hr([3]uint32{10, 40, 70}, 120, 130, 90),
+		hr([3]uint32{90, 100, 110}, 140, 150, 150),
+	)
+}
+
+func TestSwimLengths(t *testing.T) {
+	a, err := ReadActivity(bytes.NewReader(swimFile()))
+	if err != nil {
+		t.Fatalf("%s\n", err)
+	}
+
+	if pool, ok := a.PoolLength(); !ok || pool != 25 {
+		t.Errorf("got pool length %f", pool)
+	}
+
+	lengths := a.SwimLengths()
+	if len(lengths) != 4 {
+		t.Fatalf("got %d lengths", len(lengths))
+	}
+	if l := lengths[1]; !l.Active || l.Stroke != StrokeFreestyle || l.Strokes != 22 || l.Duration != 32*time.Second {
+		t.Errorf("got length %+v", l)
+	}
+	if l := lengths[2]; l.Active || l.Strokes != 0 {
+		t.Errorf("got idle length %+v", l)
+	}
+
+	for i, want := range []float64{(102 + 104 + 120) / 3.0, 130, 90, (140 + 150 + 150) / 3.0} {
+		if math.Abs(lengths[i].HeartRate-want) > 1e-9 {
+			t.Errorf("length %d: got heart rate %f, want %f", i, lengths[i].HeartRate, want)
+		}
+	}
+
+	intervals := a.SwimIntervals()
+	if len(intervals) != 2 {
+		t.Fatalf("got %d intervals", len(intervals))
+	}
+	if i := intervals[0]; len(i.Lengths) != 2 || i.Distance != 50 || i.Strokes != 42 || i.Duration != 62*time.Second || i.Rest != 20*time.Second || i.Stroke != StrokeFreestyle {
+		t.Errorf("got interval %+v", i)
+	}
+	if i := intervals[1]; i.Distance != 25 || i.Stroke.String() != "breaststroke" || i.Rest != 0 {
+		t.Errorf("got interval %+v", i)
+	}
+}
+
+func TestMergeHeartRate(t *testing.T) {
+	a, err := ReadActivity(bytes.NewReader(swimFile()))
+	if err != nil {
+		t.Fatalf("%s\n", err)
+	}
+
+	samples := a.HeartRateSamples()
+	if len(samples) != 9 || !samples[3].Time.Equal(timeOf(1000000010)) || samples[3].BPM != 120 {
+		t.Fatalf("got samples %v", samples)
+	}
+
+	a.MergeHeartRate()
+	for i, want := range []float64{100, 109, 77} {
+		if hr, ok := a.Records[i].Float("heart_rate"); !ok || hr != want {
+			t.Errorf("record %d: got heart rate %f, want %f", i, hr, want)
+		}
+	}
+}
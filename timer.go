@@ -0,0 +1,146 @@
+package gofit
+
+import (
+	"time"
+)
+
+// Timer event types that start and stop the timer
+const (
+	eventTimer = 0
+
+	eventTypeStart          = 0
+	eventTypeStop           = 1
+	eventTypeStopAll        = 4
+	eventTypeStopDisable    = 8
+	eventTypeStopDisableAll = 9
+)
+
+// Interval is a span of time from Start up to End.
+type Interval struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Duration returns the length of the interval.
+func (i Interval) Duration() time.Duration {
+	return i.End.Sub(i.Start)
+}
+
+// Timer describes when an activity's timer was running, reconstructed from
+// its timer events and records.
+type Timer struct {
+	// Spans during which the timer was running, in order
+	Segments []Interval
+
+	// Spans between segments during which the timer was stopped, whether by
+	// the athlete or by auto pause
+	Pauses []Interval
+
+	// Gaps between consecutive records while the timer was running that are
+	// longer than the maximum gap given to NewTimer
+	Gaps []Interval
+}
+
+// NewTimer reconstructs the timer from a stream of decoded messages in file
+// order. Timer start and stop events delimit the segments; if there are no
+// timer events the timer is taken to run from the first record to the last
+// message. Record gaps longer than maxGap while the timer runs are reported
+// as Gaps, or not at all if maxGap is not positive.
+func NewTimer(messages []*Message, maxGap time.Duration) *Timer {
+	t := &Timer{}
+
+	var start, stop, lastRecord, last time.Time
+	running, events := false, false
+
+	for _, m := range messages {
+		if m.Time.IsZero() {
+			continue
+		}
+		if m.Time.After(last) {
+			last = m.Time
+		}
+
+		switch m.Type {
+		case MesgEvent:
+			event, _ := m.Float("event")
+			eventType, ok := m.Float("event_type")
+			if event != eventTimer || !ok {
+				continue
+			}
+			events = true
+
+			switch eventType {
+			case eventTypeStart:
+				if running {
+					continue
+				}
+				if !stop.IsZero() {
+					t.Pauses = append(t.Pauses, Interval{stop, m.Time})
+				}
+				start, running = m.Time, true
+				lastRecord = time.Time{}
+			case eventTypeStop, eventTypeStopAll, eventTypeStopDisable, eventTypeStopDisableAll:
+				if !running {
+					continue
+				}
+				t.Segments = append(t.Segments, Interval{start, m.Time})
+				stop, running = m.Time, false
+			}
+		case MesgRecord:
+			if !running && !events && len(t.Segments) == 0 {
+				start, running = m.Time, true
+			}
+			if !running {
+				continue
+			}
+
+			if maxGap > 0 && !lastRecord.IsZero() && m.Time.Sub(lastRecord) > maxGap {
+				t.Gaps = append(t.Gaps, Interval{lastRecord, m.Time})
+			}
+			lastRecord = m.Time
+		}
+	}
+
+	if running {
+		t.Segments = append(t.Segments, Interval{start, last})
+	}
+	return t
+}
+
+// Timer reconstructs the activity's timer. See NewTimer.
+func (a *Activity) Timer(maxGap time.Duration) *Timer {
+	return NewTimer(a.Messages, maxGap)
+}
+
+// TimerTime returns the total time the timer was running. This is what the
+// device reports as session.total_timer_time.
+func (t *Timer) TimerTime() time.Duration {
+	return total(t.Segments)
+}
+
+// PausedTime returns the total time the timer was stopped between segments.
+func (t *Timer) PausedTime() time.Duration {
+	return total(t.Pauses)
+}
+
+// MovingTime returns the time the timer was running less any record gaps.
+func (t *Timer) MovingTime() time.Duration {
+	return t.TimerTime() - total(t.Gaps)
+}
+
+// ElapsedTime returns the time from the start of the first segment to the
+// end of the last.
+func (t *Timer) ElapsedTime() time.Duration {
+	if len(t.Segments) == 0 {
+		return 0
+	}
+	return t.Segments[len(t.Segments)-1].End.Sub(t.Segments[0].Start)
+}
+
+func total(intervals []Interval) time.Duration {
+	var d time.Duration
+	for _, i := range intervals {
+		d += i.Duration()
+	}
+	return d
+}
@@ -0,0 +1,72 @@
+package gofit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimerMatchesSession(t *testing.T) {
+	for _, path := range []string{
+		"testfiles/test.fit",
+		"testfiles/test2.fit",
+		"testfiles/21497.fit",
+		"testfiles/devdata.fit",
+		"testfiles/fit2.fit",
+	} {
+		a := readActivity(t, path)
+		timer := a.Timer(0)
+
+		session := a.Sessions[0]
+		timerTime, _ := session.Float("total_timer_time")
+		elapsedTime, _ := session.Float("total_elapsed_time")
+
+		// Events are only timestamped to the second
+		if d := timer.TimerTime().Seconds() - timerTime; d < -2 || d > 2 {
+			t.Errorf("%s: got timer time %s, session has %.1fs", path, timer.TimerTime(), timerTime)
+		}
+		if d := timer.ElapsedTime().Seconds() - elapsedTime; d < -2 || d > 2 {
+			t.Errorf("%s: got elapsed time %s, session has %.1fs", path, timer.ElapsedTime(), elapsedTime)
+		}
+		if timer.TimerTime()+timer.PausedTime() != timer.ElapsedTime() {
+			t.Errorf("%s: timer and paused time should add up to the elapsed time", path)
+		}
+	}
+}
+
+func TestTimerPauses(t *testing.T) {
+	a := readActivity(t, "testfiles/test.fit")
+
+	timer := a.Timer(10 * time.Second)
+	if len(timer.Segments) != len(timer.Pauses)+1 {
+		t.Errorf("got %d segments and %d pauses", len(timer.Segments), len(timer.Pauses))
+	}
+	for i, p := range timer.Pauses {
+		if !p.Start.Equal(timer.Segments[i].End) || !p.End.Equal(timer.Segments[i+1].Start) {
+			t.Errorf("pause %d does not lie between segments", i)
+		}
+	}
+
+	if len(timer.Gaps) == 0 || timer.MovingTime() >= timer.TimerTime() {
+		t.Errorf("got %d gaps, moving time %s", len(timer.Gaps), timer.MovingTime())
+	}
+	if moving := a.Timer(0).MovingTime(); moving != timer.TimerTime() {
+		t.Errorf("gaps should not be detected without a maximum, got moving time %s", moving)
+	}
+}
+
+func TestTimerWithoutEvents(t *testing.T) {
+	const full = 1000000000
+
+	data := testFile(
+		testDefinition(0, 0, MesgRecord, [3]byte{253, 4, TypeUint32}),
+		testData(0, le32(full)),
+		testData(0, le32(full+1)),
+		testData(0, le32(full+60)),
+		testData(0, le32(full+61)),
+	)
+
+	timer := NewTimer(decodeAll(t, data), 5*time.Second)
+	if timer.TimerTime() != 61*time.Second || timer.MovingTime() != 2*time.Second {
+		t.Errorf("got timer time %s, moving time %s", timer.TimerTime(), timer.MovingTime())
+	}
+}
@@ -0,0 +1,73 @@
+package gofit
+
+import (
+	"time"
+)
+
+// Timestamps are seconds since GetEpoch. Values below this are relative to
+// the device's power up rather than absolute.
+const minAbsoluteTimestamp = 0x10000000
+
+// timeOf converts a FIT timestamp to a time.Time.
+func timeOf(timestamp uint32) time.Time {
+	return GetEpoch().Add(time.Duration(timestamp) * time.Second)
+}
+
+// timestamps reconstructs full timestamps for messages that only carry the
+// low bits of one, relative to the last full timestamp seen in the stream.
+type timestamps struct {
+	last  uint32
+	valid bool
+}
+
+// resolve sets m.Time. A message with a timestamp field updates the last
+// full timestamp. Otherwise a compressed timestamp header, a timestamp_16 or
+// a timestamp_min_8 field is rolled forward from it and added to the message
+// as an expanded timestamp field.
+func (ts *timestamps) resolve(m *Message, compressed bool, timeOffset byte) {
+	if f := m.FieldNum(253); f != nil && f.Raw != nil {
+		if v, ok := f.Value.(float64); ok {
+			ts.last = uint32(v)
+			ts.valid = true
+			m.Time = timeOf(ts.last)
+		}
+		return
+	}
+
+	if !ts.valid {
+		return
+	}
+
+	var t uint32
+	if compressed {
+		ts.last += (uint32(timeOffset) - ts.last) & 0x1F
+		t = ts.last
+	} else if t16, ok := m.Float("timestamp_16"); ok {
+		ts.last += (uint32(t16) - ts.last) & 0xFFFF
+		t = ts.last
+	} else if t8, ok := m.Float("timestamp_min_8"); ok {
+		// Only whole minutes are known, so the last timestamp only moves
+		// on if the minute is later than it
+		minutes := ts.last / 60
+		minutes += (uint32(t8) - minutes) & 0xFF
+		t = minutes * 60
+		if t > ts.last {
+			ts.last = t
+		}
+	} else {
+		return
+	}
+
+	m.Time = timeOf(t)
+	m.Fields = append(m.Fields, Field{Num: 253, Name: "timestamp", Units: "s", Type: TypeUint32, Value: float64(t)})
+}
+
+// Timestamp converts the value of a date_time field such as start_time to
+// a time.Time. It returns false if the message has no valid value for it.
+func (m *Message) Timestamp(name string) (time.Time, bool) {
+	v, ok := m.Float(name)
+	if !ok || v < minAbsoluteTimestamp {
+		return time.Time{}, false
+	}
+	return timeOf(uint32(v)), true
+}
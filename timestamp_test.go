@@ -0,0 +1,136 @@
+package gofit
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestRecordTimes(t *testing.T) {
+	data, err := os.ReadFile("testfiles/test2.fit")
+	if err != nil {
+		t.Fatalf("%s\n", err)
+	}
+
+	var records []*Message
+	for _, m := range decodeAll(t, data) {
+		if m.Type == MesgSession {
+			if start, ok := m.Timestamp("start_time"); !ok || start.Unix() != 1431558100 {
+				t.Errorf("got session start %s", start)
+			}
+		}
+		if m.Type == MesgRecord {
+			records = append(records, m)
+		}
+	}
+
+	// As in TestPower
+	if records[0].Time.Unix() != 1431558100 || records[5].Time.Unix() != 1431558105 {
+		t.Errorf("got record times %s and %s", records[0].Time, records[5].Time)
+	}
+	for _, m := range records {
+		if m.Time.Location() != time.UTC {
+			t.Errorf("record times should be UTC")
+			break
+		}
+	}
+}
+
+func TestTimestamp16(t *testing.T) {
+	const full = 1000000000
+
+	t16 := func(elapsed uint32) []byte {
+		return le16(uint16(full + elapsed))
+	}
+
+	// timestamp_min_8 holds the low byte of the minutes
+	minutes := uint32(full+80000) / 60
+
+	data := testFile(
+		testDefinition(0, 0, MesgMonitoringInfo, [3]byte{253, 4, TypeUint32}, [3]byte{0, 4, TypeUint32}),
+		testData(0, le32(full), le32(full+3600)),
+		testDefinition(1, 0, MesgMonitoring, [3]byte{26, 2, TypeUint16}, [3]byte{27, 1, TypeUint8}),
+		testData(1, t16(60), []byte{55}),
+		testData(1, t16(65000), []byte{56}),
+		testData(1, t16(70000), []byte{57}),
+		testDefinition(2, 0, MesgMonitoring, [3]byte{25, 1, TypeUint8}),
+		testData(2, []byte{byte(minutes)}),
+	)
+
+	messages := decodeAll(t, data)
+
+	want := []uint32{0, 60, 65000, 70000, minutes*60 - full}
+	for i, m := range messages {
+		if !m.Time.Equal(timeOf(full + want[i])) {
+			t.Errorf("message %d: got %s, want %s", i, m.Time, timeOf(full+want[i]))
+		}
+		if v, ok := m.Float("timestamp"); !ok || v != float64(full+want[i]) {
+			t.Errorf("message %d: got timestamp %f", i, v)
+		}
+	}
+
+	if local, ok := messages[0].Timestamp("local_timestamp"); !ok || local.Sub(messages[0].Time) != time.Hour {
+		t.Errorf("got local timestamp %s", local)
+	}
+}
+
+func TestTimestampMin8Anchor(t *testing.T) {
+	// 40 seconds past the minute, and divisible by 32
+	const full = 1000000000
+
+	data := testFile(
+		testDefinition(0, 0, MesgRecord, [3]byte{253, 4, TypeUint32}),
+		testData(0, le32(full)),
+		testDefinition(1, 0, MesgMonitoring, [3]byte{25, 1, TypeUint8}),
+		testData(1, []byte{full / 60 & 0xFF}),
+		testDefinition(2, 0, MesgRecord, [3]byte{7, 2, TypeUint16}),
+		testData(0x80|2<<5|5, le16(100)),
+	)
+
+	messages := decodeAll(t, data)
+
+	// The minute holding the full timestamp, which still anchors the record
+	// after it
+	if want := timeOf(full / 60 * 60); !messages[1].Time.Equal(want) {
+		t.Errorf("got monitoring time %s, want %s", messages[1].Time, want)
+	}
+	if want := timeOf(full + 5); !messages[2].Time.Equal(want) {
+		t.Errorf("got record time %s, want %s", messages[2].Time, want)
+	}
+}
+
+func TestCompressedTimestampHeader(t *testing.T) {
+	// Full timestamp divisible by 32 so the offsets are easy to follow
+	const full = 1000000000
+
+	compressed := func(local, offset byte) byte {
+		return 0x80 | local<<5 | offset
+	}
+
+	data := testFile(
+		testDefinition(0, 0, MesgRecord, [3]byte{253, 4, TypeUint32}, [3]byte{7, 2, TypeUint16}),
+		testData(0, le32(full), le16(100)),
+		testDefinition(1, 0, MesgRecord, [3]byte{7, 2, TypeUint16}),
+		testData(compressed(1, 5), le16(105)),
+		testData(compressed(1, 20), le16(120)),
+		testData(compressed(1, 3), le16(135)),
+	)
+
+	// The power doubles as the expected number of seconds past full
+	for i, m := range decodeAll(t, data) {
+		power, _ := m.Float("power")
+		if !m.Time.Equal(timeOf(full + uint32(power) - 100)) {
+			t.Errorf("message %d: got %s", i, m.Time)
+		}
+	}
+
+	idx, err := BuildIndex(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("%s\n", err)
+	}
+	records, err := idx.Between(MesgRecord, timeOf(full+10), timeOf(full+40))
+	if err != nil || len(records) != 2 {
+		t.Errorf("got %d records between 10s and 40s: %v", len(records), err)
+	}
+}
@@ -0,0 +1,428 @@
+package gofit
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// Altitude change ignored when totalling the ascent and descent of trimmed
+// laps and sessions, to keep barometer noise out of the totals
+const trimHysteresis = 3
+
+// Summary fields that cannot be recomputed from records and are cleared
+// from trimmed laps and sessions rather than left stale
+var trimStaleFields = []string{
+	"normalized_power", "training_stress_score", "intensity_factor",
+	"total_work", "time_in_hr_zone", "time_in_speed_zone",
+	"time_in_cadence_zone", "time_in_power_zone", "total_fat_calories",
+	"avg_grade", "num_lengths", "num_active_lengths", "first_length_index",
+}
+
+// Trim returns a copy of the activity with only what happened between start
+// and end. Records, events, lengths and other timed messages outside the
+// range are dropped, along with untimed messages such as hrv that follow
+// them. Laps and sessions are clipped to the records left in them and
+// their totals, averages and maxima recomputed; laps left empty are
+// dropped. Totals that cannot be recomputed from records, such as
+// normalized power and time in zone, are cleared, while calories and cycles
+// are scaled by the timer time kept. Timer events are added at either end
+// if needed so the timer runs over the trimmed records, and record
+// distances are made to start from zero.
+//
+// Messages that describe the file or the device rather than the activity,
+// such as file_id and device_info, are kept whatever their time. a itself
+// is not changed, and the trimmed activity can be written with
+// WriteActivity.
+func (a *Activity) Trim(start, end time.Time) (*Activity, error) {
+	if end.Before(start) {
+		return nil, errors.New("trim end before start")
+	}
+
+	var records []*Message
+	for _, r := range a.Records {
+		if !r.Time.IsZero() && !r.Time.Before(start) && !r.Time.After(end) {
+			records = append(records, r)
+		}
+	}
+	if len(records) == 0 {
+		return nil, errors.New("no records between trim start and end")
+	}
+	first, last := records[0].Time, records[len(records)-1].Time
+
+	// Keep a clone of every message in range, marking where the first and
+	// last records went so timer events can be added around them
+	var timerEvent *Message
+	kept := make([]*Message, 0, len(a.Messages))
+	firstRecord, lastRecord := -1, -1
+	keep := true
+
+	for _, m := range a.Messages {
+		switch m.Type {
+		case MesgLap, MesgSession:
+			if trimDrops(m, first, last) {
+				continue
+			}
+			kept = append(kept, m.clone())
+			continue
+		case MesgActivity, MesgFileID, MesgFileCreator, MesgDeviceInfo, MesgDeveloperDataID, MesgFieldDescription:
+			kept = append(kept, m.clone())
+			continue
+		case MesgTimeInZone:
+			continue
+		case MesgEvent:
+			if event, _ := m.Float("event"); event == eventTimer && timerEvent == nil {
+				timerEvent = m
+			}
+		}
+
+		if !m.Time.IsZero() {
+			keep = !m.Time.Before(first) && !m.Time.After(last)
+		}
+		if !keep {
+			continue
+		}
+
+		if m.Type == MesgRecord {
+			if firstRecord < 0 {
+				firstRecord = len(kept)
+			}
+			lastRecord = len(kept)
+		}
+		kept = append(kept, m.clone())
+	}
+
+	if timerEvent != nil {
+		kept = addTimerEvents(kept, firstRecord, lastRecord, timerEvent, first, last)
+	}
+
+	t := &Activity{}
+	for _, m := range kept {
+		t.add(m)
+	}
+
+	// Distances start from zero at the first record
+	if base, ok := t.Records[0].Float("distance"); ok {
+		for _, r := range t.Records {
+			if d, ok := r.Float("distance"); ok {
+				if err := r.addFloat("distance", d-base); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	timer := NewTimer(t.Messages, 0)
+
+	for i, lap := range t.Laps {
+		summarize(lap, t.Records, timer, first, last)
+		if err := lap.addFloat("message_index", float64(i)); err != nil {
+			return nil, err
+		}
+	}
+
+	t.summarizeSessions(timer, first, last)
+
+	return t, nil
+}
+
+// summarizeSessions recomputes the sessions of a over its records between
+// first and last, counts the laps in each and updates the activity message
+// to match.
+func (a *Activity) summarizeSessions(timer *Timer, first, last time.Time) {
+	var timerTime float64
+	for _, s := range a.Sessions {
+		summarize(s, a.Records, timer, first, last)
+
+		// Count the laps starting within the session
+		start, end := summaryStart(s), s.Time
+		numLaps, firstLap := 0, -1
+		for i, lap := range a.Laps {
+			if ls := summaryStart(lap); !ls.Before(start) && !ls.After(end) {
+				if firstLap < 0 {
+					firstLap = i
+				}
+				numLaps++
+			}
+		}
+		s.SetFloat("num_laps", float64(numLaps))
+		if firstLap >= 0 {
+			s.SetFloat("first_lap_index", float64(firstLap))
+		}
+
+		if v, ok := s.Float("total_timer_time"); ok {
+			timerTime += v
+		}
+	}
+
+	if a.Activity != nil {
+		if local, ok := a.Activity.Float("local_timestamp"); ok {
+			utc, _ := a.Activity.Float("timestamp")
+			a.Activity.SetFloat("local_timestamp", local+fitSeconds(last)-utc)
+		}
+		setTime(a.Activity, last)
+		a.Activity.SetFloat("num_sessions", float64(len(a.Sessions)))
+		if len(a.Sessions) > 0 {
+			a.Activity.SetFloat("total_timer_time", timerTime)
+		}
+	}
+}
+
+// addTimerEvents adds timer events built from template so that the timer is
+// running from the first record, at first, to the last record, at last.
+// firstRecord and lastRecord are the positions of those records in kept.
+func addTimerEvents(kept []*Message, firstRecord, lastRecord int, template *Message, first, last time.Time) []*Message {
+	starts, running, seen := false, false, false
+	for _, m := range kept {
+		if m.Type != MesgEvent {
+			continue
+		}
+		event, _ := m.Float("event")
+		eventType, ok := m.Float("event_type")
+		if event != eventTimer || !ok {
+			continue
+		}
+
+		if !seen {
+			starts = eventType == eventTypeStart && !m.Time.After(first)
+			seen = true
+		}
+		running = eventType == eventTypeStart
+	}
+
+	timerEvent := func(eventType float64, at time.Time) *Message {
+		m := template.clone()
+		m.SetFloat("event_type", eventType)
+		setTime(m, at)
+		return m
+	}
+
+	if running || !seen {
+		stop := timerEvent(eventTypeStopAll, last)
+		kept = append(kept[:lastRecord+1], append([]*Message{stop}, kept[lastRecord+1:]...)...)
+	}
+	if !starts {
+		start := timerEvent(eventTypeStart, first)
+		kept = append(kept[:firstRecord], append([]*Message{start}, kept[firstRecord:]...)...)
+	}
+
+	return kept
+}
+
+// trimDrops reports whether Trim drops the lap or session m when keeping
+// the records from first to last. Those wholly outside the range are
+// dropped, and so are those that end just as it starts, since devices that
+// end a lap at the start of the next would otherwise leave a lap clipped
+// to no time at all.
+func trimDrops(m *Message, first, last time.Time) bool {
+	start := summaryStart(m)
+	if start.After(last) {
+		return true
+	}
+	if m.Time.IsZero() {
+		return false
+	}
+	return m.Time.Before(first) || (m.Time.Equal(first) && start.Before(first))
+}
+
+// summaryStart returns the start time of a lap or session, or its time if
+// it has none.
+func summaryStart(m *Message) time.Time {
+	if start, ok := m.Timestamp("start_time"); ok {
+		return start
+	}
+	return m.Time
+}
+
+// summarize clips the lap or session m to the trimmed records between first
+// and last and recomputes its totals from them.
+func summarize(m *Message, records []*Message, timer *Timer, first, last time.Time) {
+	start, end := summaryStart(m), m.Time
+	if end.IsZero() || end.After(last) {
+		end = last
+	}
+	if start.Before(first) {
+		start = first
+	}
+
+	oldTimer, oldTimerOK := m.Float("total_timer_time")
+
+	m.SetFloat("start_time", fitSeconds(start))
+	setTime(m, end)
+	m.SetFloat("total_elapsed_time", end.Sub(start).Seconds())
+
+	var running time.Duration
+	for _, seg := range timer.Segments {
+		s, e := seg.Start, seg.End
+		if s.Before(start) {
+			s = start
+		}
+		if e.After(end) {
+			e = end
+		}
+		if e.After(s) {
+			running += e.Sub(s)
+		}
+	}
+	m.SetFloat("total_timer_time", running.Seconds())
+
+	// Calories and cycles are only known as totals, so scale them
+	if oldTimerOK && oldTimer > 0 {
+		ratio := running.Seconds() / oldTimer
+		for _, name := range []string{"total_calories", "total_cycles"} {
+			if v, ok := m.Float(name); ok {
+				m.SetFloat(name, math.Round(v*ratio))
+			}
+		}
+	}
+
+	for _, name := range trimStaleFields {
+		m.clearField(name)
+	}
+
+	var in []*Message
+	var before *Message
+	for _, r := range records {
+		if r.Time.Before(start) {
+			before = r
+			continue
+		}
+		if r.Time.After(end) {
+			break
+		}
+		in = append(in, r)
+	}
+	if len(in) == 0 {
+		return
+	}
+
+	from := in[0]
+	if before != nil {
+		from = before
+	}
+	distance, distanceOK := 0.0, false
+	if d0, ok := from.Float("distance"); ok {
+		if d1, ok := in[len(in)-1].Float("distance"); ok {
+			distance, distanceOK = d1-d0, true
+			m.SetFloat("total_distance", distance)
+		}
+	}
+
+	for _, r := range in {
+		if lat, long, ok := r.rawPosition("position"); ok {
+			m.SetFloat("start_position_lat", lat)
+			m.SetFloat("start_position_long", long)
+			break
+		}
+	}
+	for i := len(in) - 1; i >= 0; i-- {
+		if lat, long, ok := in[i].rawPosition("position"); ok {
+			m.SetFloat("end_position_lat", lat)
+			m.SetFloat("end_position_long", long)
+			break
+		}
+	}
+
+	// Cadence averages leave out coasting, as devices do
+	for _, stat := range []struct {
+		field, avg, max, min string
+	}{
+		{"heart_rate", "avg_heart_rate", "max_heart_rate", "min_heart_rate"},
+		{"cadence", "avg_cadence", "max_cadence", ""},
+		{"power", "avg_power", "max_power", ""},
+		{"enhanced_speed", "enhanced_avg_speed", "enhanced_max_speed", ""},
+		{"speed", "avg_speed", "max_speed", ""},
+		{"enhanced_altitude", "enhanced_avg_altitude", "enhanced_max_altitude", "enhanced_min_altitude"},
+		{"altitude", "avg_altitude", "max_altitude", "min_altitude"},
+	} {
+		var sum float64
+		min, max := math.Inf(1), math.Inf(-1)
+		n := 0
+		for _, r := range in {
+			v, ok := r.Float(stat.field)
+			if !ok || (v == 0 && stat.field == "cadence") {
+				continue
+			}
+			sum += v
+			min = math.Min(min, v)
+			max = math.Max(max, v)
+			n++
+		}
+		if n == 0 {
+			continue
+		}
+
+		m.SetFloat(stat.avg, sum/float64(n))
+		m.SetFloat(stat.max, max)
+		if stat.min != "" {
+			m.SetFloat(stat.min, min)
+		}
+	}
+
+	// Average speed is over the timer time rather than the records
+	if distanceOK && running > 0 {
+		m.SetFloat("avg_speed", distance/running.Seconds())
+		m.SetFloat("enhanced_avg_speed", distance/running.Seconds())
+	}
+
+	altitude := "enhanced_altitude"
+	if _, ok := in[0].Float(altitude); !ok {
+		altitude = "altitude"
+	}
+	var ascent, descent float64
+	ref, valid := 0.0, false
+	for _, r := range in {
+		alt, ok := r.Float(altitude)
+		if !ok {
+			continue
+		}
+		switch {
+		case !valid:
+			ref, valid = alt, true
+		case alt > ref+trimHysteresis:
+			ascent += alt - ref
+			ref = alt
+		case alt < ref-trimHysteresis:
+			descent += ref - alt
+			ref = alt
+		}
+	}
+	if valid {
+		m.SetFloat("total_ascent", ascent)
+		m.SetFloat("total_descent", descent)
+	}
+}
+
+// rawPosition returns the position fields called prefix+"_lat" and
+// prefix+"_long" in semicircles, as stored in the file.
+func (m *Message) rawPosition(prefix string) (lat, long float64, ok bool) {
+	lat, lok := m.Float(prefix + "_lat")
+	long, gok := m.Float(prefix + "_long")
+	return lat, long, lok && gok
+}
+
+// clearField sets the field called name to the invalid value of its type.
+func (m *Message) clearField(name string) {
+	if f := m.Field(name); f != nil {
+		clearRaw(f, byteOrder(m.Arch))
+	}
+}
+
+// setTime sets the timestamp of m, whether it was read from the file or
+// reconstructed.
+func setTime(m *Message, t time.Time) {
+	v := fitSeconds(t)
+	if !m.SetFloat("timestamp", v) {
+		if f := m.FieldNum(253); f != nil {
+			f.Value = v
+		} else {
+			m.Fields = append(m.Fields, Field{Num: 253, Name: "timestamp", Units: "s", Type: TypeUint32, Value: v})
+		}
+	}
+	m.Time = t
+}
+
+// fitSeconds converts t to a FIT timestamp.
+func fitSeconds(t time.Time) float64 {
+	return float64(t.Sub(GetEpoch()) / time.Second)
+}
@@ -0,0 +1,207 @@
+package gofit
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestTrim(t *testing.T) {
+	a := readActivity(t, "testfiles/test2.fit")
+
+	start := a.Records[300].Time
+	end := a.Records[600].Time.Add(500 * time.Millisecond)
+
+	trimmed, err := a.Trim(start, end)
+	if err != nil {
+		t.Fatalf("%s\n", err)
+	}
+	if len(a.Records) != 1309 {
+		t.Fatalf("trimming changed the activity")
+	}
+
+	var buf bytes.Buffer
+	if err := WriteActivity(&buf, trimmed); err != nil {
+		t.Fatalf("%s\n", err)
+	}
+
+	data := buf.Bytes()
+	if size := binary.LittleEndian.Uint32(data[4:8]); int(size) != len(data)-16 {
+		t.Errorf("got data size %d for %d bytes", size, len(data))
+	}
+	if crc := CRC(data); crc != 0 {
+		t.Errorf("got file CRC %04x", crc)
+	}
+
+	b, err := ReadActivity(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("%s\n", err)
+	}
+	if len(b.Records) != 301 || !b.Records[0].Time.Equal(start) || !b.Records[300].Time.Equal(a.Records[600].Time) {
+		t.Fatalf("got %d records from %s", len(b.Records), b.Records[0].Time)
+	}
+	if len(b.Sessions) != 1 || len(b.Laps) != 1 || b.Activity == nil {
+		t.Fatalf("missing summary messages")
+	}
+
+	want := a.Records[600].Time.Sub(start)
+
+	s := b.Sessions[0]
+	if got, _ := s.Timestamp("start_time"); !got.Equal(start) {
+		t.Errorf("got session start %s", got)
+	}
+	if elapsed, _ := s.Float("total_elapsed_time"); elapsed != want.Seconds() {
+		t.Errorf("got elapsed time %f", elapsed)
+	}
+	if timer, _ := s.Float("total_timer_time"); timer != want.Seconds() {
+		t.Errorf("got timer time %f", timer)
+	}
+
+	// Distances start again from zero
+	d0, _ := a.Records[300].Float("distance")
+	d1, _ := a.Records[600].Float("distance")
+	if got, _ := b.Records[300].Float("distance"); math.Abs(got-(d1-d0)) > 0.01 {
+		t.Errorf("got last distance %f, want %f", got, d1-d0)
+	}
+	if got, _ := s.Float("total_distance"); math.Abs(got-(d1-d0)) > 0.01 {
+		t.Errorf("got total distance %f, want %f", got, d1-d0)
+	}
+
+	maxPower := 0.0
+	for _, r := range b.Records {
+		if p, ok := r.Float("power"); ok {
+			maxPower = math.Max(maxPower, p)
+		}
+	}
+	if got, _ := s.Float("max_power"); got != maxPower {
+		t.Errorf("got max power %f, want %f", got, maxPower)
+	}
+	if _, ok := s.Float("normalized_power"); ok {
+		t.Errorf("expected normalized power to be cleared")
+	}
+
+	// The timer runs over the trimmed records
+	timer := b.Timer(0)
+	if len(timer.Segments) != 1 || !timer.Segments[0].Start.Equal(start) || timer.TimerTime() != want {
+		t.Errorf("got timer segments %v", timer.Segments)
+	}
+	if !b.Activity.Time.Equal(a.Records[600].Time) {
+		t.Errorf("got activity time %s", b.Activity.Time)
+	}
+}
+
+func TestTrimWholeActivity(t *testing.T) {
+	// Trimming nothing off should leave the device's totals close to as
+	// they were
+	for _, path := range []string{"testfiles/test.fit", "testfiles/devdata.fit"} {
+		a := readActivity(t, path)
+		b, err := a.Trim(a.Records[0].Time, a.Records[len(a.Records)-1].Time)
+		if err != nil {
+			t.Fatalf("%s: %s", path, err)
+		}
+		if len(b.Records) != len(a.Records) || len(b.Laps) != len(a.Laps) {
+			t.Fatalf("%s: got %d records and %d laps", path, len(b.Records), len(b.Laps))
+		}
+
+		for name, tolerance := range map[string]float64{
+			"total_elapsed_time": 0.01,
+			"total_timer_time":   0.01,
+			"total_distance":     0.01,
+			"avg_speed":          0.01,
+			"max_heart_rate":     0.01,
+			"avg_heart_rate":     0.05,
+			"avg_cadence":        0.05,
+			"total_ascent":       0.15,
+		} {
+			want, _ := a.Sessions[0].Float(name)
+			got, _ := b.Sessions[0].Float(name)
+			if math.Abs(got-want) > tolerance*want {
+				t.Errorf("%s: got %s %f, want %f", path, name, got, want)
+			}
+		}
+	}
+}
+
+func TestTrimLapEndingAtStart(t *testing.T) {
+	a := readActivity(t, "testfiles/devdata.fit")
+
+	// End the second lap as the third starts, as some devices do
+	start := summaryStart(a.Laps[2])
+	setTime(a.Laps[1], start)
+
+	found := false
+	for _, r := range a.Records {
+		found = found || r.Time.Equal(start)
+	}
+	if !found {
+		t.Fatalf("no record at the start of the third lap")
+	}
+
+	b, err := a.Trim(start, a.Records[len(a.Records)-1].Time)
+	if err != nil {
+		t.Fatalf("%s\n", err)
+	}
+	if len(b.Laps) != len(a.Laps)-2 {
+		t.Fatalf("got %d laps, want %d", len(b.Laps), len(a.Laps)-2)
+	}
+	if got := summaryStart(b.Laps[0]); !got.Equal(start) {
+		t.Errorf("first lap starts at %s, want %s", got, start)
+	}
+	if elapsed, _ := b.Laps[0].Float("total_elapsed_time"); elapsed == 0 {
+		t.Errorf("kept a lap with no elapsed time")
+	}
+}
+
+func TestTrimAddsFields(t *testing.T) {
+	a := readActivity(t, "testfiles/test2.fit")
+	stripFields(a)
+
+	b, err := a.Trim(a.Records[300].Time, a.Records[600].Time)
+	if err != nil {
+		t.Fatalf("%s\n", err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteActivity(&buf, b); err != nil {
+		t.Fatalf("%s\n", err)
+	}
+	if b, err = ReadActivity(&buf); err != nil {
+		t.Fatalf("%s\n", err)
+	}
+
+	if index, ok := b.Laps[0].Float("message_index"); !ok || index != 0 {
+		t.Errorf("got lap index %f", index)
+	}
+	if d, ok := b.Records[0].Float("distance"); !ok || d != 0 {
+		t.Errorf("got first distance %f", d)
+	}
+}
+
+// stripFields removes the message_index of a's laps and leaves its record
+// distances as if expanded from components rather than read from the file.
+func stripFields(a *Activity) {
+	for _, lap := range a.Laps {
+		if i := lap.fieldIndex(254); i >= 0 {
+			lap.Fields = append(lap.Fields[:i], lap.Fields[i+1:]...)
+		}
+	}
+	for _, r := range a.Records {
+		if f := r.Field("distance"); f != nil {
+			f.Raw = nil
+		}
+	}
+}
+
+func TestTrimErrors(t *testing.T) {
+	a := readActivity(t, "testfiles/test2.fit")
+	start := a.StartTime()
+
+	if _, err := a.Trim(start.Add(time.Hour), start); err == nil {
+		t.Errorf("expected an error for end before start")
+	}
+	if _, err := a.Trim(start.Add(-time.Hour), start.Add(-time.Minute)); err == nil {
+		t.Errorf("expected an error with no records")
+	}
+}
@@ -0,0 +1,155 @@
+// Package units converts values between the units used by the FIT profile
+// and those people usually want to see.
+package units
+
+import (
+	"math"
+	"time"
+)
+
+// System is a system of units to present decoded values in.
+type System int
+
+const (
+	// FIT leaves values in the units given by the FIT profile
+	FIT System = iota
+
+	// Metric presents positions in degrees, speeds in km/h and distances
+	// in km
+	Metric
+
+	// Imperial presents positions in degrees, speeds in mph, distances in
+	// miles, heights in feet, temperatures in Fahrenheit and weights in
+	// pounds
+	Imperial
+)
+
+const (
+	metersPerKilometer = 1000
+	metersPerMile      = 1609.344
+	metersPerFoot      = 0.3048
+	millimetersPerInch = 25.4
+	kilogramsPerPound  = 0.45359237
+
+	// There are 2^31 semicircles in 180 degrees
+	semicirclesPerDegree = (1 << 31) / 180.0
+)
+
+// Fields holding the distance covered so far or in total, which are shown
+// in kilometers or miles rather than as lengths
+var distanceFields = map[string]bool{
+	"distance":       true,
+	"total_distance": true,
+}
+
+// Degrees converts an angle in semicircles, as positions are stored, to
+// degrees.
+func Degrees(semicircles float64) float64 {
+	return semicircles / semicirclesPerDegree
+}
+
+// Semicircles converts an angle in degrees to semicircles.
+func Semicircles(degrees float64) float64 {
+	return degrees * semicirclesPerDegree
+}
+
+// KilometersPerHour converts a speed in m/s to km/h.
+func KilometersPerHour(mps float64) float64 {
+	return mps * 3600 / metersPerKilometer
+}
+
+// MilesPerHour converts a speed in m/s to mph.
+func MilesPerHour(mps float64) float64 {
+	return mps * 3600 / metersPerMile
+}
+
+// Kilometers converts a distance in meters to kilometers.
+func Kilometers(m float64) float64 {
+	return m / metersPerKilometer
+}
+
+// Miles converts a distance in meters to miles.
+func Miles(m float64) float64 {
+	return m / metersPerMile
+}
+
+// Feet converts a length in meters to feet.
+func Feet(m float64) float64 {
+	return m / metersPerFoot
+}
+
+// Inches converts a length in millimeters to inches.
+func Inches(mm float64) float64 {
+	return mm / millimetersPerInch
+}
+
+// Fahrenheit converts a temperature in degrees Celsius to Fahrenheit.
+func Fahrenheit(c float64) float64 {
+	return c*9/5 + 32
+}
+
+// Pounds converts a weight in kilograms to pounds.
+func Pounds(kg float64) float64 {
+	return kg / kilogramsPerPound
+}
+
+// Pace converts a speed in m/s to the time taken to cover a kilometer, or a
+// mile in the Imperial system. It returns 0 if the speed is not positive.
+func Pace(mps float64, sys System) time.Duration {
+	if mps <= 0 || math.IsNaN(mps) {
+		return 0
+	}
+
+	meters := float64(metersPerKilometer)
+	if sys == Imperial {
+		meters = metersPerMile
+	}
+	return time.Duration(meters / mps * float64(time.Second))
+}
+
+// Convert converts value of the field called name, in the profile units
+// from, to the system sys. It returns the converted value and its units.
+// Values in units the system does not change are returned as they are.
+//
+// The cumulative and total distances of records, laps and sessions are
+// converted to kilometers or miles. Other lengths in meters, such as
+// altitudes and avg_stroke_distance, are converted to feet in the Imperial
+// system and left in meters otherwise.
+func Convert(name string, value float64, from string, sys System) (float64, string) {
+	if sys == FIT {
+		return value, from
+	}
+
+	switch from {
+	case "semicircles":
+		return Degrees(value), "deg"
+	case "m/s":
+		if sys == Imperial {
+			return MilesPerHour(value), "mph"
+		}
+		return KilometersPerHour(value), "km/h"
+	case "m":
+		if distanceFields[name] {
+			if sys == Imperial {
+				return Miles(value), "mi"
+			}
+			return Kilometers(value), "km"
+		}
+		if sys == Imperial {
+			return Feet(value), "ft"
+		}
+	case "mm":
+		if sys == Imperial {
+			return Inches(value), "in"
+		}
+	case "C":
+		if sys == Imperial {
+			return Fahrenheit(value), "F"
+		}
+	case "kg":
+		if sys == Imperial {
+			return Pounds(value), "lb"
+		}
+	}
+	return value, from
+}
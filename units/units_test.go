@@ -0,0 +1,68 @@
+package units
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+func TestDegrees(t *testing.T) {
+	if d := Degrees(1 << 30); d != 90 {
+		t.Errorf("got %f degrees", d)
+	}
+	if d := Degrees(-(1 << 31)); d != -180 {
+		t.Errorf("got %f degrees", d)
+	}
+	if s := Semicircles(Degrees(495280430)); !near(s, 495280430) {
+		t.Errorf("got %f semicircles", s)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name  string
+		value float64
+		from  string
+		sys   System
+		want  float64
+		units string
+	}{
+		{"speed", 10, "m/s", FIT, 10, "m/s"},
+		{"speed", 10, "m/s", Metric, 36, "km/h"},
+		{"speed", 10, "m/s", Imperial, 22.369363, "mph"},
+		{"total_distance", 42195, "m", Metric, 42.195, "km"},
+		{"distance", 1609.344, "m", Imperial, 1, "mi"},
+		{"avg_stroke_distance", 2.5, "m", Metric, 2.5, "m"},
+		{"avg_stroke_distance", 3.048, "m", Imperial, 10, "ft"},
+		{"altitude", 100, "m", Metric, 100, "m"},
+		{"altitude", 3.048, "m", Imperial, 10, "ft"},
+		{"avg_temperature", 20, "C", Imperial, 68, "F"},
+		{"avg_temperature", 20, "C", Metric, 20, "C"},
+		{"position_lat", 1 << 30, "semicircles", Metric, 90, "deg"},
+		{"weight", 0.45359237, "kg", Imperial, 1, "lb"},
+		{"heart_rate", 150, "bpm", Imperial, 150, "bpm"},
+	}
+
+	for _, test := range tests {
+		v, u := Convert(test.name, test.value, test.from, test.sys)
+		if !near(v, test.want) || u != test.units {
+			t.Errorf("%s %f %s: got %f %s, want %f %s", test.name, test.value, test.from, v, u, test.want, test.units)
+		}
+	}
+}
+
+func TestPace(t *testing.T) {
+	if p := Pace(1000.0/300, Metric); p.Round(time.Millisecond) != 5*time.Minute {
+		t.Errorf("got pace %s", p)
+	}
+	if p := Pace(metersPerMile/480, Imperial); p.Round(time.Millisecond) != 8*time.Minute {
+		t.Errorf("got pace %s", p)
+	}
+	if p := Pace(0, Metric); p != 0 {
+		t.Errorf("stationary pace should be 0, got %s", p)
+	}
+}